@@ -0,0 +1,94 @@
+package dogfetch
+
+import "github.com/rommms07/dogfetch/internal/utils"
+
+// CacheStore holds the pages fetched by a client, keyed by the sha512 sum of their url. Get
+// and Stat return an error wrapping ErrCacheMiss when there is no entry for a key. The pages
+// are kept in files under DefaultCacheDir unless WithCacheStore says otherwise.
+type CacheStore = utils.CacheStore
+
+// CachePeeker is implemented by the stores which can be inspected without being changed: the
+// access times of the pages are left as they were, and the incomplete pages are reported with
+// ErrCacheIncomplete rather than discarded. The stores of this package all implement it.
+type CachePeeker = utils.Peeker
+
+type (
+	// CacheEntry is a cached page: the metadata of its response and its body.
+	CacheEntry = utils.Entry
+
+	// CacheEntryInfo describes a cached page without reading its body.
+	CacheEntryInfo = utils.EntryInfo
+
+	// PruneStats tells how many cached pages a prune removed, and the size they took.
+	PruneStats = utils.PruneStats
+
+	FileStore    = utils.FileStore
+	MemoryStore  = utils.MemoryStore
+	ArchiveStore = utils.ArchiveStore
+)
+
+const (
+	// CodecGzip stores the bodies of the cached pages gzip-compressed.
+	CodecGzip = utils.CodecGzip
+
+	// CodecIdentity stores the bodies of the cached pages as they were received.
+	CodecIdentity = utils.CodecIdentity
+)
+
+// CacheKey returns the key of the page at pageUrl in a CacheStore.
+func CacheKey(pageUrl string) string {
+	return utils.CacheKey(pageUrl)
+}
+
+// CacheDirEnv is the environment variable naming the default cache directory.
+const CacheDirEnv = utils.CacheDirEnv
+
+// DefaultCacheDir returns the directory of the pages cached by the clients without a
+// CacheStore or a cache directory: the one named by $DOGFETCH_CACHE_DIR, or else dogfetch in
+// the cache directory of the user, $XDG_CACHE_HOME/dogfetch on Linux. An error is returned when
+// neither can be located.
+func DefaultCacheDir() (string, error) {
+	return utils.DefaultCachePath()
+}
+
+// ErrCacheMiss is reported by a CacheStore which has no entry for a key.
+var ErrCacheMiss = utils.ErrCacheMiss
+
+// ErrCacheIncomplete is reported by a CachePeeker for a page which was not completely written.
+var ErrCacheIncomplete = utils.ErrIncomplete
+
+// NewFileStore returns a store keeping every page in a pair of files under dir, <key>.json
+// holding the metadata of the response and <key>.cache its body.
+func NewFileStore(dir string) *FileStore {
+	return utils.NewFileStore(dir)
+}
+
+// NewMemoryStore returns a store keeping the pages in memory, for the programs which cannot
+// write to the disk. The pages are fetched again by every new store.
+func NewMemoryStore() *MemoryStore {
+	return utils.NewMemoryStore()
+}
+
+// OpenArchiveStore opens a store keeping every page in the single file at path, to which the
+// pages are only appended. It is created if it does not exist, and must be closed once the
+// clients using it are done.
+func OpenArchiveStore(path string) (*ArchiveStore, error) {
+	return utils.OpenArchiveStore(path)
+}
+
+// OpenArchiveStoreReadOnly opens the store in the file at path without ever changing it, so
+// it fails to put or delete any page. It must be closed once done with.
+func OpenArchiveStoreReadOnly(path string) (*ArchiveStore, error) {
+	return utils.OpenArchiveStoreReadOnly(path)
+}
+
+// PruneCache removes the pages used the least recently from the cache of the client, until
+// the remaining ones take at most maxSize bytes.
+func (c *Client) PruneCache(maxSize int64) (PruneStats, error) {
+	store, err := c.cache.OpenStore()
+	if err != nil {
+		return PruneStats{}, err
+	}
+
+	return utils.Prune(store, maxSize)
+}
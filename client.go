@@ -0,0 +1,526 @@
+package dogfetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/rommms07/dogfetch/internal/utils"
+)
+
+// Usually making a series of concurrent HTTP request into the server causes an Internal Server Error
+// on the server, to avoid that issue, we limit the number of concurrent request up to N. By default
+// we limit the number of parallel HTTP request by 50.
+const defaultConcurrency = 50
+
+// The references of the breeds are fetched from many hosts at once, but no more than
+// defaultRefConcurrency at a time.
+const defaultRefConcurrency = 8
+
+// The requests made to every host are limited by a token bucket. The hosts of the sources are
+// crawled faster than the hosts of the references, which are only visited a few times each.
+const (
+	DefaultRateLimit = 10
+	DefaultRateBurst = 10
+
+	DefaultReferenceRateLimit = 2
+	DefaultReferenceRateBurst = 4
+)
+
+// DefaultUserAgent identifies the requests of the clients, unless WithUserAgent says
+// otherwise. Its product token is the RobotsAgent.
+const DefaultUserAgent = utils.DefaultUserAgent
+
+// DefaultCacheTTL is how long the fetched pages are used before they are revalidated with their
+// source, unless WithCacheTTL says otherwise.
+const DefaultCacheTTL = utils.DefaultTTL
+
+// Client owns a dataset of dog breeds along with everything needed to build it: the HTTP
+// client, the response cache and the crawler settings. Nothing is fetched until Load or
+// Refresh is called.
+type Client struct {
+	mu     sync.RWMutex
+	loadMu sync.Mutex
+	breeds BreedInfos
+	pages  map[string]*SnapshotPage
+	loaded bool
+
+	// extracted is the extractor the breeds of the dataset were extracted with, as recorded
+	// in SnapshotHeader.
+	extracted string
+
+	httpClient  *http.Client
+	transport   http.RoundTripper
+	proxy       *url.URL
+	timeout     time.Duration
+	dialTimeout time.Duration
+	userAgent   string
+	header      http.Header
+	cache       *utils.Cache
+	sources     []Source
+	priority    []BreedSource
+	concurrency int
+	refConc     int
+	rate        *utils.Limiter
+	refRate     *utils.Limiter
+	sourceHosts map[string]bool
+	cacheStore  CacheStore
+	cacheDir    string
+	cacheTTL    time.Duration
+	cacheCodec  string
+	cacheMax    int64
+	retry       RetryPolicy
+	robots      *robotsCache
+	lastCrawl   *CrawlReport
+	progress    func(Event)
+	progressMu  sync.Mutex
+
+	snapshotPath *string
+	loadMode     LoadMode
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used to fetch the breed pages and their references.
+// The client is not modified by the other options, which apply to a copy of it.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithTransport sets the transport through which the requests are made, in place of the one
+// of the HTTP client.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = rt
+	}
+}
+
+// WithProxy routes the requests through the proxy at proxyUrl, instead of the one told by the
+// $HTTP_PROXY and $HTTPS_PROXY environment variables. It applies to the default transport, or
+// to a custom *http.Transport, but not to any other RoundTripper.
+func WithProxy(proxyUrl *url.URL) Option {
+	return func(c *Client) {
+		c.proxy = proxyUrl
+	}
+}
+
+// WithTimeout gives up the requests which take more than d, reading the body included. A
+// request given up is retried as any failed request. There is no timeout by default.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithDialTimeout gives up the connections which cannot be made within d. Like WithProxy, it
+// applies to the default transport or to a custom *http.Transport.
+func WithDialTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.dialTimeout = d
+	}
+}
+
+// WithUserAgent sets the User-Agent identifying the requests, DefaultUserAgent by default.
+// The robots.txt rules are still the ones of the RobotsAgent.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithHeader adds a header sent along with every request. The User-Agent header is set by
+// WithUserAgent instead.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		if c.header == nil {
+			c.header = make(http.Header)
+		}
+
+		c.header.Add(key, value)
+	}
+}
+
+// WithSources sets the sources crawled by the client, replacing the DefaultSources.
+func WithSources(sources ...Source) Option {
+	return func(c *Client) {
+		c.sources = sources
+	}
+}
+
+// WithSourcePriority sets the order in which the sources win over each other when they
+// disagree on a field of a breed. It defaults to the order of the sources of the client, and
+// the sources missing from names come after the ones listed.
+func WithSourcePriority(names ...BreedSource) Option {
+	return func(c *Client) {
+		c.priority = names
+	}
+}
+
+// WithConcurrency sets the maximum number of breed pages crawled in parallel.
+func WithConcurrency(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// WithReferenceConcurrency sets the maximum number of references fetched in parallel.
+func WithReferenceConcurrency(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.refConc = n
+		}
+	}
+}
+
+// WithRateLimit limits the requests made to each host of the sources to rate per second, with
+// bursts of up to burst requests. It is DefaultRateLimit by default, and a rate of zero or less
+// does not limit the requests.
+func WithRateLimit(rate float64, burst int) Option {
+	return func(c *Client) {
+		c.rate = utils.NewLimiter(rate, burst)
+	}
+}
+
+// WithReferenceRateLimit limits the requests made to each host of the references, every host
+// which is not one of a source, like WithRateLimit. It is DefaultReferenceRateLimit by
+// default.
+func WithReferenceRateLimit(rate float64, burst int) Option {
+	return func(c *Client) {
+		c.refRate = utils.NewLimiter(rate, burst)
+	}
+}
+
+// WithRetryPolicy sets how the failed requests are retried, DefaultRetryPolicy by default.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithCacheStore sets where the fetched pages are cached, in files under DefaultCacheDir by
+// default. It takes precedence over WithCacheDir.
+func WithCacheStore(store CacheStore) Option {
+	return func(c *Client) {
+		c.cacheStore = store
+	}
+}
+
+// WithCacheDir keeps the fetched pages in files under dir instead of DefaultCacheDir. The
+// directory is created once a page is written into it.
+func WithCacheDir(dir string) Option {
+	return func(c *Client) {
+		c.cacheDir = dir
+	}
+}
+
+// WithCacheTTL sets how long the fetched pages are used before they are revalidated with
+// their source, DefaultCacheTTL by default. A negative ttl revalidates them every time.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithCacheCodec sets how the bodies of the fetched pages are stored in the cache,
+// CodecGzip by default. The pages cached with another codec are still read.
+func WithCacheCodec(codec string) Option {
+	return func(c *Client) {
+		c.cacheCodec = codec
+	}
+}
+
+// WithCacheMaxSize caps the size of the cache to maxSize bytes, evicting the pages which were
+// used the least recently once it is exceeded. The cache is not capped by default.
+func WithCacheMaxSize(maxSize int64) Option {
+	return func(c *Client) {
+		c.cacheMax = maxSize
+	}
+}
+
+func New(opts ...Option) *Client {
+	c := &Client{
+		breeds:      make(BreedInfos),
+		httpClient:  http.DefaultClient,
+		sources:     DefaultSources(),
+		concurrency: defaultConcurrency,
+		refConc:     defaultRefConcurrency,
+		rate:        utils.NewLimiter(DefaultRateLimit, DefaultRateBurst),
+		refRate:     utils.NewLimiter(DefaultReferenceRateLimit, DefaultReferenceRateBurst),
+		userAgent:   DefaultUserAgent,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.cache = utils.NewCache(c.newHTTPClient())
+	c.cache.UserAgent = c.userAgent
+	c.cache.Header = c.header
+	c.cache.TTL = c.cacheTTL
+	c.cache.Codec = c.cacheCodec
+	c.cache.MaxSize = c.cacheMax
+	c.cache.Wait = c.wait
+	c.cache.Retry = c.retry
+	c.robots = newRobotsCache(c.cache)
+
+	if c.progress != nil {
+		c.cache.Observe = c.observeCache
+	}
+
+	c.sourceHosts = make(map[string]bool)
+	for _, src := range c.sources {
+		if u, err := url.Parse(string(src.Name())); err == nil {
+			c.sourceHosts[u.Host] = true
+		}
+	}
+
+	if c.cacheStore != nil {
+		c.cache.Store = c.cacheStore
+	} else if len(c.cacheDir) != 0 {
+		c.cache.Store = NewFileStore(c.cacheDir)
+	}
+	return c
+}
+
+// newHTTPClient returns the HTTP client of the client, changed as told by the options.
+func (c *Client) newHTTPClient() *http.Client {
+	if c.transport == nil && c.proxy == nil && c.timeout == 0 && c.dialTimeout == 0 {
+		return c.httpClient
+	}
+
+	hc := &http.Client{}
+	if c.httpClient != nil {
+		*hc = *c.httpClient
+	}
+
+	if c.transport != nil {
+		hc.Transport = c.transport
+	}
+
+	if c.timeout != 0 {
+		hc.Timeout = c.timeout
+	}
+
+	if c.proxy == nil && c.dialTimeout == 0 {
+		return hc
+	}
+
+	rt := hc.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return hc
+	}
+
+	t = t.Clone()
+	if c.proxy != nil {
+		t.Proxy = http.ProxyURL(c.proxy)
+	}
+
+	if c.dialTimeout != 0 {
+		t.DialContext = (&net.Dialer{Timeout: c.dialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	}
+
+	hc.Transport = t
+	return hc
+}
+
+// Load populates the dataset of the client as told by its LoadMode, from the snapshot saved
+// by a previous crawl or by crawling the sources by default. Calling Load on an already loaded
+// client does nothing. The dataset is left untouched if ctx is done before the crawl is
+// finished, or if the snapshot is corrupt. A crawled dataset is still loaded if its snapshot
+// cannot be saved, the error being returned all the same.
+//
+// In the LoadLive mode, when there is no saved snapshot and none of the sources can be reached,
+// the embedded snapshot is loaded instead if the package has one, the *CrawlError being
+// returned all the same. Refresh crawls the sources again later on.
+func (c *Client) Load(ctx context.Context) error {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+
+	c.mu.RLock()
+	loaded := c.loaded
+	c.mu.RUnlock()
+
+	if loaded {
+		return nil
+	}
+
+	switch c.loadMode {
+	case LoadEmbedded:
+		s, err := EmbeddedSnapshot()
+		if err != nil {
+			return err
+		}
+
+		c.set(s)
+		return nil
+
+	case LoadEmbeddedThenRefresh:
+		// A corrupt saved snapshot is replaced once the refresh succeeds.
+		s, err := c.savedSnapshot()
+		if s == nil || err != nil {
+			if s, err = EmbeddedSnapshot(); errors.Is(err, ErrNoEmbeddedSnapshot) {
+				return c.Refresh(ctx)
+			} else if err != nil {
+				return err
+			}
+		}
+
+		c.set(s)
+		return c.Refresh(ctx)
+	}
+
+	s, err := c.fetchDogBreeds(ctx)
+
+	var crawlErr *CrawlError
+	if errors.As(err, &crawlErr) && crawlErr.Unreachable && ctx.Err() == nil {
+		if embedded, embeddedErr := EmbeddedSnapshot(); embeddedErr == nil {
+			c.set(embedded)
+			return err
+		}
+	}
+
+	if err != nil && !isSnapshotWrite(err) {
+		return err
+	}
+
+	c.set(s)
+	return err
+}
+
+// Refresh crawls the sources again and replaces the dataset of the client with the result,
+// which is saved as its snapshot. The dataset is left untouched if ctx is done before the
+// crawl is finished, or if any of the breed pages could not be crawled.
+func (c *Client) Refresh(ctx context.Context) error {
+	cr := newCrawler(c)
+
+	breeds, err := cr.run(ctx)
+	if err != nil {
+		return err
+	}
+
+	s := c.newSnapshot(breeds, cr.pages())
+	c.set(s)
+	return c.saveSnapshotFile(s)
+}
+
+// Crawl crawls the sources without touching the dataset of the client. If ctx is done
+// before the crawl is finished, the breeds crawled so far are returned along with ctx.Err().
+// Breed pages which could not be crawled are reported through a *CrawlError, in which case
+// the breeds of the other pages are still returned.
+func (c *Client) Crawl(ctx context.Context) (BreedInfos, error) {
+	return newCrawler(c).run(ctx)
+}
+
+// CrawlReport tells how a crawl went. The pages whose robots.txt disallows RobotsAgent to
+// fetch them are skipped, and listed in Blocked by url instead. The pages of an Attacher
+// source whose breed matches no other breed are listed in Unmatched, and the hosts which could
+// not be crawled at all in Unavailable.
+type CrawlReport struct {
+	Started     time.Time `json:"started"`
+	Finished    time.Time `json:"finished"`
+	Pages       int       `json:"pages"`
+	Blocked     []string  `json:"blocked"`
+	Unmatched   []string  `json:"unmatched"`
+	Unavailable []string  `json:"unavailable"`
+	Errors      []error   `json:"-"`
+}
+
+// LastCrawl returns the report of the last crawl made by the client, or nil if it has not
+// crawled anything yet.
+func (c *Client) LastCrawl() *CrawlReport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.lastCrawl
+}
+
+func (c *Client) observeCache(resUrl string, hit bool) {
+	if hit {
+		c.emit(&CacheHit{URL: resUrl})
+	} else {
+		c.emit(&CacheMiss{URL: resUrl})
+	}
+}
+
+func (c *Client) setLastCrawl(r *CrawlReport) {
+	c.mu.Lock()
+	c.lastCrawl = r
+	c.mu.Unlock()
+}
+
+// wait waits until a request can be made to the host of u, as told by the Crawl-delay of its
+// robots.txt and the rate limit of the host.
+func (c *Client) wait(ctx context.Context, u *url.URL) error {
+	if err := c.robots.wait(ctx, u); err != nil {
+		return err
+	}
+
+	if c.sourceHosts[u.Host] {
+		return c.rate.Wait(ctx, u.Host)
+	}
+
+	return c.refRate.Wait(ctx, u.Host)
+}
+
+func (c *Client) sourcePriority() []BreedSource {
+	priority := append([]BreedSource{}, c.priority...)
+	for _, src := range c.sources {
+		priority = append(priority, src.Name())
+	}
+
+	return priority
+}
+
+func (c *Client) set(s *Snapshot) {
+	c.mu.Lock()
+	c.breeds = s.Breeds
+	c.pages = s.Pages
+	c.extracted = s.Header.Extractor
+	c.loaded = true
+	c.mu.Unlock()
+}
+
+// GetById returns the breed with the given id, or ErrNotFound if there is no such breed.
+func (c *Client) GetById(id string) (*BreedInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if bi := c.breeds[id]; bi != nil {
+		return bi, nil
+	}
+
+	return nil, fmt.Errorf("%w (id: %s)", ErrNotFound, id)
+}
+
+// GetByName returns the breed with the given name, or ErrNotFound if there is no such breed.
+func (c *Client) GetByName(name string) (*BreedInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if bi := c.breeds.GetByName(name); bi != nil {
+		return bi, nil
+	}
+
+	return nil, fmt.Errorf("%w (name: %s)", ErrNotFound, name)
+}
+
+func (c *Client) GetAll() BreedInfos {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.breeds
+}
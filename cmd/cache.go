@@ -0,0 +1,409 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rommms07/dogfetch"
+)
+
+const cacheUsage = `Usage: dogfetch cache [-dir dir | -archive file] <command> [arguments]
+
+Commands:
+  ls                                      list the cached pages
+  show [-body] <url|key>                  show a cached page
+  purge [-expired] [-url-pattern regexp]  remove the cached pages, all of them by default
+  stats                                   summarize the cache
+  verify                                  check that every cached page can be read back
+`
+
+// runCache runs the cache subcommand with its arguments, writing its output into w.
+func runCache(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("cache", flag.ExitOnError)
+	dir := fs.String("dir", "", "Directory of the cache. (default $"+dogfetch.CacheDirEnv+", or else the dogfetch directory in the cache directory of the user)")
+	archive := fs.String("archive", "", "Use the cache archived in this file instead of a directory.")
+	fs.Usage = func() { fmt.Fprint(fs.Output(), cacheUsage); fs.PrintDefaults() }
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("missing cache command")
+	}
+
+	cmd, args := fs.Arg(0), fs.Args()[1:]
+
+	// Only purge changes the cache, the other commands leave it as they found it.
+	var store cacheStore
+
+	if len(*archive) != 0 {
+		open := dogfetch.OpenArchiveStoreReadOnly
+		if cmd == "purge" {
+			open = dogfetch.OpenArchiveStore
+		}
+
+		a, err := open(*archive)
+		if err != nil {
+			return err
+		}
+
+		defer a.Close()
+		store = a
+	} else {
+		if len(*dir) == 0 {
+			var err error
+			if *dir, err = dogfetch.DefaultCacheDir(); err != nil {
+				return err
+			}
+		}
+
+		store = dogfetch.NewFileStore(*dir)
+	}
+
+	switch cmd {
+	case "ls":
+		return cacheLs(store, w)
+	case "show":
+		return cacheShow(store, w, args)
+	case "purge":
+		return cachePurge(store, w, args)
+	case "stats":
+		return cacheStats(store, w)
+	case "verify":
+		return cacheVerify(store, w)
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown cache command: %s", cmd)
+}
+
+// cacheStore is a store which the cache subcommands can also inspect without changing it.
+type cacheStore interface {
+	dogfetch.CacheStore
+	dogfetch.CachePeeker
+}
+
+// cacheInfos describes every complete entry of the store, sorted by url.
+func cacheInfos(store dogfetch.CachePeeker) ([]*dogfetch.CacheEntryInfo, error) {
+	keys, err := store.PeekList()
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []*dogfetch.CacheEntryInfo
+	for _, key := range keys {
+		info, err := store.PeekStat(key)
+		if errors.Is(err, dogfetch.ErrCacheMiss) || errors.Is(err, dogfetch.ErrCacheIncomplete) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].URL != infos[j].URL {
+			return infos[i].URL < infos[j].URL
+		}
+
+		return infos[i].Key < infos[j].Key
+	})
+
+	return infos, nil
+}
+
+func cacheLs(store dogfetch.CachePeeker, w io.Writer) error {
+	infos, err := cacheInfos(store)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "URL\tSTATUS\tSIZE\tAGE\tEXPIRES")
+
+	for _, info := range infos {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", entryName(info), info.Status, byteSize(info.Size), age(info.FetchedAt), expiry(info.ExpiresAt))
+	}
+
+	return tw.Flush()
+}
+
+func cacheShow(store dogfetch.CachePeeker, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	body := fs.Bool("body", false, "Print the body of the page.")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: cache show [-body] <url|key>")
+	}
+
+	key := fs.Arg(0)
+	if strings.Contains(key, "://") {
+		key = dogfetch.CacheKey(key)
+	}
+
+	e, err := store.Peek(key)
+	if errors.Is(err, dogfetch.ErrCacheMiss) {
+		return fmt.Errorf("not cached: %s", fs.Arg(0))
+	} else if errors.Is(err, dogfetch.ErrCacheIncomplete) {
+		return fmt.Errorf("incomplete page: %s", fs.Arg(0))
+	} else if err != nil {
+		return err
+	}
+
+	P, err := e.Decode()
+	if err != nil {
+		return err
+	}
+
+	info, err := store.PeekStat(key)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "URL:\t%s\n", entryName(info))
+	fmt.Fprintf(tw, "Key:\t%s\n", key)
+
+	if e.Meta.Response != nil {
+		fmt.Fprintf(tw, "Status:\t%s\n", e.Meta.Status)
+	}
+
+	fmt.Fprintf(tw, "Fetched:\t%s (%s ago)\n", info.FetchedAt.Format(time.RFC1123), age(info.FetchedAt))
+	fmt.Fprintf(tw, "Expires:\t%s (%s)\n", info.ExpiresAt.Format(time.RFC1123), expiry(info.ExpiresAt))
+	fmt.Fprintf(tw, "Size:\t%s stored, %s decoded (%s)\n", byteSize(info.Size), byteSize(int64(len(P))), info.Codec)
+
+	if e.Meta.Response != nil {
+		keys := make([]string, 0, len(e.Meta.Header))
+		for key := range e.Meta.Header {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		fmt.Fprintln(tw, "Headers:")
+
+		for _, key := range keys {
+			fmt.Fprintf(tw, "  %s:\t%s\n", key, strings.Join(e.Meta.Header[key], ", "))
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if *body {
+		fmt.Fprintf(w, "\n%s\n", P)
+	}
+
+	return nil
+}
+
+func cachePurge(store cacheStore, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	expired := fs.Bool("expired", false, "Only remove the expired pages.")
+	pattern := fs.String("url-pattern", "", "Only remove the pages whose url matches this regular expression.")
+	fs.Parse(args)
+
+	var patt *regexp.Regexp
+	if len(*pattern) != 0 {
+		var err error
+		if patt, err = regexp.Compile(*pattern); err != nil {
+			return err
+		}
+	}
+
+	// Listing the entries with List also removes the files left behind by incomplete writes.
+	if _, err := store.List(); err != nil {
+		return err
+	}
+
+	infos, err := cacheInfos(store)
+	if err != nil {
+		return err
+	}
+
+	var stats dogfetch.PruneStats
+
+	for _, info := range infos {
+		if *expired && info.ExpiresAt.After(time.Now()) {
+			continue
+		}
+
+		if patt != nil && !patt.MatchString(info.URL) {
+			continue
+		}
+
+		if err := store.Delete(info.Key); err != nil {
+			return err
+		}
+
+		stats.Entries++
+		stats.Bytes += info.Size
+	}
+
+	fmt.Fprintf(w, "purged %d pages (%s)\n", stats.Entries, byteSize(stats.Bytes))
+	return nil
+}
+
+func cacheStats(store dogfetch.CachePeeker, w io.Writer) error {
+	infos, err := cacheInfos(store)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	var expired, unknown int
+	var oldest, newest time.Time
+
+	hosts := make(map[string]int)
+	codecs := make(map[string]int)
+
+	for _, info := range infos {
+		size += info.Size
+		codecs[info.Codec]++
+
+		if !info.ExpiresAt.After(time.Now()) {
+			expired++
+		}
+
+		if u, err := url.Parse(info.URL); err == nil && len(info.URL) != 0 {
+			hosts[u.Host]++
+		} else {
+			unknown++
+		}
+
+		if oldest.IsZero() || info.FetchedAt.Before(oldest) {
+			oldest = info.FetchedAt
+		}
+
+		if info.FetchedAt.After(newest) {
+			newest = info.FetchedAt
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "Pages:\t%d (%d expired)\n", len(infos), expired)
+	fmt.Fprintf(tw, "Size:\t%s\n", byteSize(size))
+
+	if len(infos) != 0 {
+		fmt.Fprintf(tw, "Oldest:\t%s ago\n", age(oldest))
+		fmt.Fprintf(tw, "Newest:\t%s ago\n", age(newest))
+	}
+
+	fmt.Fprintf(tw, "Codecs:\t%s\n", counts(codecs))
+	fmt.Fprintf(tw, "Hosts:\t%s\n", counts(hosts))
+
+	if unknown != 0 {
+		fmt.Fprintf(tw, "Unknown urls:\t%d\n", unknown)
+	}
+
+	return tw.Flush()
+}
+
+func cacheVerify(store dogfetch.CachePeeker, w io.Writer) error {
+	keys, err := store.PeekList()
+	if err != nil {
+		return err
+	}
+
+	var problems int
+	report := func(key, format string, a ...any) {
+		problems++
+		fmt.Fprintf(w, "%s: %s\n", key, fmt.Sprintf(format, a...))
+	}
+
+	for _, key := range keys {
+		e, err := store.Peek(key)
+		if errors.Is(err, dogfetch.ErrCacheIncomplete) {
+			report(key, "incomplete page")
+			continue
+		} else if errors.Is(err, dogfetch.ErrCacheMiss) {
+			// Deleted since it was listed.
+			continue
+		} else if err != nil {
+			report(key, "cannot read page: %v", err)
+			continue
+		}
+
+		if _, err := e.Decode(); err != nil {
+			report(key, "cannot decode body: %v", err)
+		}
+
+		if u := e.Meta.Res_url; len(u) != 0 && dogfetch.CacheKey(u) != key {
+			report(key, "key does not match the url %s", u)
+		}
+	}
+
+	fmt.Fprintf(w, "verified %d pages, %d problems\n", len(keys), problems)
+
+	if problems != 0 {
+		return fmt.Errorf("%d problems found in the cache", problems)
+	}
+
+	return nil
+}
+
+func entryName(info *dogfetch.CacheEntryInfo) string {
+	if len(info.URL) != 0 {
+		return info.URL
+	}
+
+	key := info.Key
+	if len(key) > 16 {
+		key = key[:16]
+	}
+
+	return "(unknown url) " + key
+}
+
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func age(t time.Time) string {
+	return time.Since(t).Round(time.Second).String()
+}
+
+func expiry(t time.Time) string {
+	d := time.Until(t).Round(time.Second)
+	if d <= 0 {
+		return fmt.Sprintf("expired %s ago", -d)
+	}
+
+	return "in " + d.String()
+}
+
+func counts(M map[string]int) string {
+	keys := make([]string, 0, len(M))
+	for key := range M {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s %d", key, M[key])
+	}
+
+	return strings.Join(parts, ", ")
+}
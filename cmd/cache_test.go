@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rommms07/dogfetch"
+	"github.com/rommms07/dogfetch/internal/utils"
+)
+
+const (
+	freshUrl      = "https://www.dogbreedslist.info/all-dog-breeds/border-collie.html"
+	expiredUrl    = "https://www.dogbreedslist.info/all-dog-breeds/yorkshire-terrier.html"
+	incompleteUrl = "https://www.dogbreedslist.info/all-dog-breeds/beagle.html"
+)
+
+// newTestCache fills a cache directory with a fresh page, an expired one and an incomplete
+// one, along with a body left without metadata.
+func newTestCache(t *testing.T) string {
+	dir := t.TempDir()
+	store := dogfetch.NewFileStore(dir)
+
+	for pageUrl, expires := range map[string]time.Time{
+		freshUrl:      time.Now().Add(time.Hour),
+		expiredUrl:    time.Now().Add(-time.Hour),
+		incompleteUrl: time.Now().Add(time.Hour),
+	} {
+		e := &dogfetch.CacheEntry{
+			Meta: &utils.CacheResponse{
+				Res_url:  pageUrl,
+				E_at:     expires,
+				F_at:     time.Now().Add(-2 * time.Hour),
+				Response: &http.Response{StatusCode: 200, Status: "200 OK", Header: http.Header{"Etag": {`"1"`}}},
+			},
+			Body: []byte("body of " + pageUrl),
+		}
+
+		if err := store.Put(dogfetch.CacheKey(pageUrl), e); err != nil {
+			t.Fatalf("(fail) Unable to put the page: %s (err: %v)", pageUrl, err)
+		}
+	}
+
+	os.Truncate(filepath.Join(dir, dogfetch.CacheKey(incompleteUrl)+".cache"), 4)
+	os.WriteFile(filepath.Join(dir, "orphan.cache"), []byte("orphaned body"), 0640)
+
+	// The pages were last accessed long ago.
+	accessed := time.Now().Add(-time.Hour).Round(time.Second)
+	for _, pageUrl := range []string{freshUrl, expiredUrl, incompleteUrl} {
+		os.Chtimes(filepath.Join(dir, dogfetch.CacheKey(pageUrl)+".json"), accessed, accessed)
+	}
+
+	return dir
+}
+
+// listCache lists the files of a cache directory, along with their modification times.
+func listCache(t *testing.T, dir string) map[string]time.Time {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	M := make(map[string]time.Time)
+	for _, f := range files {
+		if fi, err := f.Info(); err == nil && !f.IsDir() {
+			M[f.Name()] = fi.ModTime()
+		}
+	}
+
+	return M
+}
+
+func Test_runCache_readOnly(t *testing.T) {
+	dir := newTestCache(t)
+	files := listCache(t, dir)
+
+	tests := []struct {
+		args     []string
+		contains []string
+		failed   bool
+	}{
+		{[]string{"ls"}, []string{freshUrl, expiredUrl, "expired 1h0m0s ago"}, false},
+		{[]string{"show", "-body", freshUrl}, []string{"200 OK", "Etag:", "body of " + freshUrl}, false},
+		{[]string{"show", expiredUrl}, []string{expiredUrl, "expired 1h0m0s ago"}, false},
+		{[]string{"stats"}, []string{"2 (1 expired)", "www.dogbreedslist.info 2"}, false},
+		{[]string{"verify"}, []string{dogfetch.CacheKey(incompleteUrl) + ": incomplete page", "verified 3 pages, 1 problems"}, true},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		err := runCache(append([]string{"-dir", dir}, test.args...), &out)
+
+		if failed := err != nil; failed != test.failed {
+			t.Errorf("(fail) Did not expect the result of the command. (input: %v, err: %v)", test.args, err)
+		}
+
+		for _, s := range test.contains {
+			if !strings.Contains(out.String(), s) {
+				t.Errorf("(fail) Expected the output to contain %q. (input: %v, output: %s)", s, test.args, out.String())
+			}
+		}
+
+		if strings.Contains(out.String(), incompleteUrl) {
+			t.Errorf("(fail) Did not expect the incomplete page in the output. (input: %v, output: %s)", test.args, out.String())
+		}
+
+		// The cache is left exactly as it was found.
+		after := listCache(t, dir)
+		if len(after) != len(files) {
+			t.Errorf("(fail) Did not expect the files of the cache to change. (input: %v, output: %v)", test.args, after)
+		}
+
+		for name, modTime := range files {
+			if !after[name].Equal(modTime) {
+				t.Errorf("(fail) Did not expect the file to change: %s (input: %v)", name, test.args)
+			}
+		}
+	}
+
+	if err := runCache([]string{"-dir", dir, "show", "https://www.dogbreedslist.info/missing.html"}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "not cached") {
+		t.Errorf("(fail) Expected a page which is not cached to fail. (err: %v)", err)
+	}
+
+	if err := runCache([]string{"-dir", dir, "show", incompleteUrl}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "incomplete page") {
+		t.Errorf("(fail) Expected an incomplete page to fail. (err: %v)", err)
+	}
+}
+
+func Test_runCache_purge(t *testing.T) {
+	dir := newTestCache(t)
+	store := dogfetch.NewFileStore(dir)
+
+	cached := func(pageUrl string) bool {
+		_, err := store.PeekStat(dogfetch.CacheKey(pageUrl))
+		return err == nil
+	}
+
+	var out bytes.Buffer
+	if err := runCache([]string{"-dir", dir, "purge", "-expired"}, &out); err != nil || !strings.HasPrefix(out.String(), "purged 1 pages") {
+		t.Errorf("(fail) Expected the expired page to be purged. (output: %s, err: %v)", out.String(), err)
+	}
+
+	if cached(expiredUrl) || !cached(freshUrl) {
+		t.Errorf("(fail) Expected the expired page only to be purged.")
+	}
+
+	// The files left behind are removed along the way.
+	if _, err := os.Stat(filepath.Join(dir, "orphan.cache")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("(fail) Expected the orphaned body to be removed. (err: %v)", err)
+	}
+
+	out.Reset()
+	if err := runCache([]string{"-dir", dir, "purge", "-url-pattern", "yorkshire"}, &out); err != nil || !strings.HasPrefix(out.String(), "purged 0 pages") {
+		t.Errorf("(fail) Did not expect a page to be purged. (output: %s, err: %v)", out.String(), err)
+	}
+
+	out.Reset()
+	if err := runCache([]string{"-dir", dir, "purge"}, &out); err != nil || !strings.HasPrefix(out.String(), "purged 1 pages") || cached(freshUrl) {
+		t.Errorf("(fail) Expected every page to be purged. (output: %s, err: %v)", out.String(), err)
+	}
+}
+
+func Test_runCache_archive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.archive")
+
+	// Inspecting an archive which does not exist fails rather than creating it.
+	if err := runCache([]string{"-archive", path, "ls"}, &bytes.Buffer{}); err == nil {
+		t.Errorf("(fail) Expected a missing archive to fail.")
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("(fail) Did not expect the archive to be created. (err: %v)", err)
+	}
+
+	store, err := dogfetch.OpenArchiveStore(path)
+	if err != nil {
+		t.Fatalf("(fail) Unable to open the archive. (err: %v)", err)
+	}
+
+	e := &dogfetch.CacheEntry{Meta: &utils.CacheResponse{Res_url: freshUrl, E_at: time.Now().Add(time.Hour)}, Body: []byte("body")}
+	store.Put(dogfetch.CacheKey(freshUrl), e)
+	store.Close()
+
+	var out bytes.Buffer
+	if err := runCache([]string{"-archive", path, "ls"}, &out); err != nil || !strings.Contains(out.String(), freshUrl) {
+		t.Errorf("(fail) Did not list the archived page. (output: %s, err: %v)", out.String(), err)
+	}
+
+	out.Reset()
+	if err := runCache([]string{"-archive", path, "purge"}, &out); err != nil || !strings.HasPrefix(out.String(), "purged 1 pages") {
+		t.Errorf("(fail) Expected the archived page to be purged. (output: %s, err: %v)", out.String(), err)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rommms07/dogfetch"
+)
+
+const diffUsage = `Usage: dogfetch diff [-format text|json|markdown] <old snapshot> <new snapshot>
+`
+
+// runDiff runs the diff subcommand, which tells the changes between two snapshots of the
+// breeds, writing the report into w.
+func runDiff(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "text", "Format of the report: text, json or markdown.")
+	fs.Usage = func() { fmt.Fprint(fs.Output(), diffUsage); fs.PrintDefaults() }
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("expected two snapshots")
+	}
+
+	render, exists := diffFormats[*format]
+	if !exists {
+		return fmt.Errorf("unknown format: %s", *format)
+	}
+
+	var snapshots [2]*dogfetch.Snapshot
+	for i, path := range fs.Args() {
+		s, err := readSnapshot(path)
+		if err != nil {
+			return err
+		}
+
+		snapshots[i] = s
+	}
+
+	return render(w, dogfetch.Diff(snapshots[0].Breeds, snapshots[1].Breeds))
+}
+
+func readSnapshot(path string) (*dogfetch.Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	s, err := dogfetch.ReadSnapshot(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+var diffFormats = map[string]func(w io.Writer, r dogfetch.DiffReport) error{
+	"text":     diffText,
+	"json":     diffJSON,
+	"markdown": diffMarkdown,
+}
+
+func diffText(w io.Writer, r dogfetch.DiffReport) error {
+	if r.Empty() {
+		_, err := fmt.Fprintln(w, "no changes")
+		return err
+	}
+
+	for _, bi := range r.Added {
+		fmt.Fprintf(w, "+ %s (%s)\n", bi.Name, bi.Id)
+	}
+
+	for _, bi := range r.Removed {
+		fmt.Fprintf(w, "- %s (%s)\n", bi.Name, bi.Id)
+	}
+
+	for _, bd := range r.Changed {
+		fmt.Fprintf(w, "~ %s (%s)\n", bd.Name, bd.Id)
+
+		for _, fc := range bd.Fields {
+			fmt.Fprintf(w, "    %s: %s\n", fc.Field, fieldChange(fc))
+		}
+	}
+
+	return nil
+}
+
+func diffJSON(w io.Writer, r dogfetch.DiffReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+}
+
+func diffMarkdown(w io.Writer, r dogfetch.DiffReport) error {
+	if r.Empty() {
+		_, err := fmt.Fprintln(w, "No changes.")
+		return err
+	}
+
+	breeds := func(title string, list []*dogfetch.BreedInfo) {
+		if len(list) == 0 {
+			return
+		}
+
+		fmt.Fprintf(w, "## %s\n\n", title)
+		for _, bi := range list {
+			fmt.Fprintf(w, "- %s\n", bi.Name)
+		}
+
+		fmt.Fprintln(w)
+	}
+
+	breeds("Added breeds", r.Added)
+	breeds("Removed breeds", r.Removed)
+
+	if len(r.Changed) != 0 {
+		fmt.Fprintf(w, "## Changed breeds\n\n")
+	}
+
+	for _, bd := range r.Changed {
+		fmt.Fprintf(w, "### %s\n\n", bd.Name)
+
+		for _, fc := range bd.Fields {
+			fmt.Fprintf(w, "- **%s**: %s\n", fc.Field, fieldChange(fc))
+		}
+
+		fmt.Fprintln(w)
+	}
+
+	return nil
+}
+
+// fieldChange renders the change of a field on a single line.
+func fieldChange(fc *dogfetch.FieldChange) string {
+	if fc.Added != nil || fc.Removed != nil {
+		var parts []string
+		if len(fc.Added) != 0 {
+			parts = append(parts, "added "+strings.Join(fc.Added, ", "))
+		}
+
+		if len(fc.Removed) != 0 {
+			parts = append(parts, "removed "+strings.Join(fc.Removed, ", "))
+		}
+
+		return strings.Join(parts, "; ")
+	}
+
+	return fmt.Sprintf("%s -> %s", fieldValue(fc.Before), fieldValue(fc.After))
+}
+
+func fieldValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "(none)"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []uint64:
+		bounds := make([]string, len(v))
+		for i, b := range v {
+			bounds[i] = fmt.Sprint(b)
+		}
+
+		return strings.Join(bounds, "-")
+	case int64:
+		return fmt.Sprint(v)
+	}
+
+	P, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(P)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/rommms07/dogfetch"
+)
+
+// sourcesByName are the sources which the -sources flag can name.
+var sourcesByName = map[string]func() dogfetch.Source{
+	"dogbreedslist": func() dogfetch.Source { return &dogfetch.DogBreedsList{} },
+	"infodogs":      func() dogfetch.Source { return &dogfetch.InfoDogs{} },
+	"kennelclub":    func() dogfetch.Source { return &dogfetch.KennelClub{} },
+}
+
+// crawlFlags defines the flags setting how the sources are crawled on fs. The options they
+// give are returned by the function returned, once fs is parsed.
+func crawlFlags(fs *flag.FlagSet) func() []dogfetch.Option {
+	concurrency := fs.Int("concurrency", 0, "Maximum number of breed pages crawled in parallel. (default 50)")
+	refConcurrency := fs.Int("ref-concurrency", 0, "Maximum number of references fetched in parallel. (default 8)")
+	rate := fs.Float64("rate", dogfetch.DefaultRateLimit, "Maximum number of requests per second to each host of the sources, 0 for no limit.")
+	burst := fs.Int("burst", dogfetch.DefaultRateBurst, "Maximum burst of requests to each host of the sources.")
+	refRate := fs.Float64("ref-rate", dogfetch.DefaultReferenceRateLimit, "Maximum number of requests per second to each host of the references, 0 for no limit.")
+	refBurst := fs.Int("ref-burst", dogfetch.DefaultReferenceRateBurst, "Maximum burst of requests to each host of the references.")
+	userAgent := fs.String("user-agent", dogfetch.DefaultUserAgent, "User-Agent identifying the requests.")
+	timeout := fs.Duration("timeout", 0, "Maximum duration of a request, 0 for no timeout.")
+	progress := fs.Bool("progress", false, "Render the progress of the crawl on stderr.")
+
+	var proxy *url.URL
+	fs.Func("proxy", "`Url` of the proxy the requests are routed through.", func(s string) (err error) {
+		proxy, err = url.Parse(s)
+		return
+	})
+
+	var sources []dogfetch.Source
+	fs.Func("sources", "Comma-separated `names` of the sources crawled, among dogbreedslist, infodogs and kennelclub, whose classification is only attached to the breeds of the other sources. (default dogbreedslist)", func(s string) error {
+		sources = nil
+		for _, name := range strings.Split(s, ",") {
+			newSource := sourcesByName[strings.TrimSpace(name)]
+			if newSource == nil {
+				return fmt.Errorf("unknown source %q", name)
+			}
+
+			sources = append(sources, newSource())
+		}
+
+		return nil
+	})
+
+	var rules *dogfetch.Rules
+	fs.Func("rules", "Extract the dogbreedslist.info pages with the rules of this `file` instead of the built-in ones.", func(path string) (err error) {
+		rules, err = dogfetch.LoadRulesFile(path)
+		return
+	})
+
+	var headers [][2]string
+	fs.Func("header", "Extra header sent along with every request, as `Key: Value`. May be repeated.", func(s string) error {
+		key, val, found := strings.Cut(s, ":")
+		if !found || len(strings.TrimSpace(key)) == 0 {
+			return errors.New("expected Key: Value")
+		}
+
+		headers = append(headers, [2]string{strings.TrimSpace(key), strings.TrimSpace(val)})
+		return nil
+	})
+
+	return func() []dogfetch.Option {
+		var opts []dogfetch.Option
+
+		set := make(map[string]bool)
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+		if rules != nil && sources == nil {
+			sources = dogfetch.DefaultSources()
+		}
+
+		for _, src := range sources {
+			if dbl, ok := src.(*dogfetch.DogBreedsList); ok && rules != nil {
+				dbl.Rules = rules
+			}
+		}
+
+		if sources != nil {
+			opts = append(opts, dogfetch.WithSources(sources...))
+		}
+
+		if set["concurrency"] {
+			opts = append(opts, dogfetch.WithConcurrency(*concurrency))
+		}
+
+		if set["ref-concurrency"] {
+			opts = append(opts, dogfetch.WithReferenceConcurrency(*refConcurrency))
+		}
+
+		if set["rate"] || set["burst"] {
+			opts = append(opts, dogfetch.WithRateLimit(*rate, *burst))
+		}
+
+		if set["ref-rate"] || set["ref-burst"] {
+			opts = append(opts, dogfetch.WithReferenceRateLimit(*refRate, *refBurst))
+		}
+
+		if set["user-agent"] {
+			opts = append(opts, dogfetch.WithUserAgent(*userAgent))
+		}
+
+		if set["timeout"] {
+			opts = append(opts, dogfetch.WithTimeout(*timeout))
+		}
+
+		if proxy != nil {
+			opts = append(opts, dogfetch.WithProxy(proxy))
+		}
+
+		for _, hdr := range headers {
+			opts = append(opts, dogfetch.WithHeader(hdr[0], hdr[1]))
+		}
+
+		if *progress {
+			opts = append(opts, dogfetch.WithProgress((&progressBar{w: os.Stderr}).event))
+		}
+
+		return opts
+	}
+}
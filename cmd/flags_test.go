@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rommms07/dogfetch"
+)
+
+func Test_crawlFlags_sources(t *testing.T) {
+	tests := []struct {
+		args    []string
+		sources []dogfetch.BreedSource
+	}{
+		{nil, []dogfetch.BreedSource{dogfetch.Source1}},
+		{[]string{"-sources", "infodogs, dogbreedslist"}, []dogfetch.BreedSource{dogfetch.Source2, dogfetch.Source1}},
+		{[]string{"-sources", "dogbreedslist,kennelclub"}, []dogfetch.BreedSource{dogfetch.Source1, dogfetch.Source3}},
+		{[]string{"-rules", "../testdata/rules/dogbreedslist-renamed.json"}, []dogfetch.BreedSource{dogfetch.Source1}},
+	}
+
+	for _, test := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		opts := crawlFlags(fs)
+
+		if err := fs.Parse(test.args); err != nil {
+			t.Fatalf("(fail) Unable to parse the flags. (input: %v, err: %v)", test.args, err)
+		}
+
+		c := dogfetch.New(opts()...)
+		if sources := c.Snapshot().Header.Sources; !reflect.DeepEqual(sources, test.sources) {
+			t.Errorf("(fail) Did not expect the sources. (input: %v, output: %v)", test.args, sources)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"-sources", "dogbreedslist,unknown"},
+		{"-rules", "../testdata/rules/missing.json"},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(&strings.Builder{})
+		crawlFlags(fs)
+
+		if err := fs.Parse(args); err == nil {
+			t.Errorf("(fail) Expected the flags to fail. (input: %v)", args)
+		}
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/rommms07/dogfetch"
 )
@@ -12,18 +14,73 @@ import (
 var idParam = flag.String("id", "", "Get breed by id.")
 var nameParam = flag.String("name", "", "Get breed by name. (ex: ./cmd -name \"Golden Retriever\")")
 var allFlag = flag.Bool("all", false, "Get all dog breeds.")
+var loadParam = flag.String("load", "live", "Where the breeds come from: live (the last crawl, or else a new crawl), embedded (the breeds shipped with dogfetch) or embedded-refresh (the last crawl or the embedded breeds, refreshed by a new crawl).")
+
+var crawlOpts = crawlFlags(flag.CommandLine)
+
+var loadModes = map[string]dogfetch.LoadMode{
+	"live":             dogfetch.LoadLive,
+	"embedded":         dogfetch.LoadEmbedded,
+	"embedded-refresh": dogfetch.LoadEmbeddedThenRefresh,
+}
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		if err := runCache(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "snapshot" {
+		if err := runSnapshot(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "refresh" {
+		if err := runRefresh(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if err := runDiff(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
 	flag.Parse()
 
+	if len(*idParam) == 0 && len(*nameParam) == 0 && !*allFlag {
+		flag.Usage()
+		return
+	}
+
+	c, err := newClient()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	var res any
 
 	if len(*idParam) != 0 {
-		res = dogfetch.GetById(*idParam)
+		res, err = c.GetById(*idParam)
 	} else if len(*nameParam) != 0 {
-		res = dogfetch.GetByName(*nameParam)
+		res, err = c.GetByName(*nameParam)
 	} else if *allFlag {
-		res = dogfetch.GetAll()
+		res = c.GetAll()
+	}
+
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	P, err := json.Marshal(res)
@@ -33,3 +90,32 @@ func main() {
 
 	fmt.Println(string(P))
 }
+
+func newClient() (*dogfetch.Client, error) {
+	opts := crawlOpts()
+
+	mode, exists := loadModes[*loadParam]
+	if !exists {
+		return nil, fmt.Errorf("unknown load mode: %s", *loadParam)
+	}
+
+	if mode != dogfetch.LoadLive {
+		opts = append(opts, dogfetch.WithLoadMode(mode))
+	}
+
+	c := dogfetch.Default()
+	if len(opts) != 0 {
+		c = dogfetch.New(opts...)
+	}
+
+	if err := c.Load(context.Background()); err != nil {
+		// The breeds are still usable when the sources cannot be crawled or the snapshot saved.
+		if len(c.GetAll()) == 0 {
+			return nil, err
+		}
+
+		log.Printf("loaded the breeds with an error: %v", err)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rommms07/dogfetch"
+)
+
+// progressBar renders the progress of the crawls on a single line of w, redrawn at most every
+// progressInterval. The events are given one at a time by the client.
+type progressBar struct {
+	w     io.Writer
+	drawn time.Time
+
+	queued, done, failed int
+	refs, hits, misses   int
+}
+
+const (
+	progressInterval = 100 * time.Millisecond
+	progressWidth    = 30
+)
+
+func (pb *progressBar) event(e dogfetch.Event) {
+	switch e := e.(type) {
+	case *dogfetch.CrawlStarted:
+		*pb = progressBar{w: pb.w}
+	case *dogfetch.BreedPagesQueued:
+		pb.queued += e.Count
+	case *dogfetch.BreedPageFinished:
+		pb.done++
+	case *dogfetch.BreedPageFailed:
+		pb.done++
+		pb.failed++
+	case *dogfetch.ReferenceFetched:
+		pb.refs++
+	case *dogfetch.CacheHit:
+		pb.hits++
+	case *dogfetch.CacheMiss:
+		pb.misses++
+	case *dogfetch.CrawlFinished:
+		pb.draw()
+		fmt.Fprintln(pb.w)
+		return
+	}
+
+	if time.Since(pb.drawn) >= progressInterval {
+		pb.draw()
+	}
+}
+
+func (pb *progressBar) draw() {
+	filled := 0
+	if pb.queued != 0 {
+		filled = pb.done * progressWidth / pb.queued
+	}
+
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", progressWidth-filled)
+	line := fmt.Sprintf("[%s] %d/%d pages, %d failed, %d refs, cache %d hits %d misses",
+		bar, pb.done, pb.queued, pb.failed, pb.refs, pb.hits, pb.misses)
+
+	// The line is padded to clear what is left of a longer previous one.
+	fmt.Fprintf(pb.w, "\r%-90s", line)
+	pb.drawn = time.Now()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/rommms07/dogfetch"
+)
+
+// runRefresh runs the refresh subcommand, which refreshes the saved snapshot of the breeds by
+// extracting only the breed pages changed since, and prints what changed into w.
+func runRefresh(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("refresh", flag.ExitOnError)
+	crawlOpts := crawlFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dogfetch refresh [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+
+	c := dogfetch.New(crawlOpts()...)
+
+	sum, err := c.RefreshIncremental(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "pages: %d fetched, %d unchanged, %d removed\n", len(sum.FetchedPages), sum.UnchangedPages, len(sum.RemovedPages))
+	fmt.Fprintf(w, "breeds: %d added, %d changed, %d removed\n", len(sum.Added), len(sum.Changed), len(sum.Removed))
+
+	for _, list := range []struct {
+		mark string
+		ids  []string
+	}{{"+", sum.Added}, {"~", sum.Changed}, {"-", sum.Removed}} {
+		for _, id := range list.ids {
+			fmt.Fprintf(w, "%s %s\n", list.mark, id)
+		}
+	}
+
+	// The pages disallowed by robots.txt were skipped.
+	if r := c.LastCrawl(); r != nil && len(r.Blocked) != 0 {
+		fmt.Fprintf(w, "blocked by robots.txt: %d\n", len(r.Blocked))
+		for _, pageUrl := range r.Blocked {
+			fmt.Fprintf(w, "! %s\n", pageUrl)
+		}
+	}
+
+	return nil
+}
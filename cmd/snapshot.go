@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/rommms07/dogfetch"
+)
+
+// runSnapshot runs the snapshot subcommand, which crawls the sources and saves the breeds as a
+// snapshot, into w unless a file is given. It is how the embedded snapshot is generated.
+func runSnapshot(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	out := fs.String("o", "", "Save the snapshot into this file instead of the standard output.")
+	crawlOpts := crawlFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dogfetch snapshot [-o file] [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+
+	c := dogfetch.New(append(crawlOpts(), dogfetch.WithSnapshotPath(""))...)
+
+	// A snapshot is only made of a complete crawl.
+	if err := c.Refresh(context.Background()); err != nil {
+		return err
+	}
+
+	s := c.Snapshot()
+	if len(*out) != 0 {
+		return s.WriteFile(*out)
+	}
+
+	_, err := s.WriteTo(w)
+	return err
+}
@@ -0,0 +1,222 @@
+package dogfetch
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// DiffReport tells the changes from a dataset to another one, the breeds being matched by id.
+type DiffReport struct {
+	Added   []*BreedInfo `json:"added"`
+	Removed []*BreedInfo `json:"removed"`
+	Changed []*BreedDiff `json:"changed"`
+}
+
+// BreedDiff lists the changed fields of a breed found in both datasets.
+type BreedDiff struct {
+	Id     string         `json:"id"`
+	Name   string         `json:"name"`
+	Fields []*FieldChange `json:"fields"`
+}
+
+// FieldChange is the change of a field of a breed, named by its json name. The set-like fields,
+// such as otherNames or colors, tell the values they gained and lost in Added and Removed, their
+// order being irrelevant. The other fields tell their Before and After values. The scores of
+// breedChars are compared one by one, in fields named breedChars[<characteristic>].
+type FieldChange struct {
+	Field   string   `json:"field"`
+	Before  any      `json:"before,omitempty"`
+	After   any      `json:"after,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Empty tells whether the datasets compared are the same.
+func (r DiffReport) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// Diff compares the dataset a to the dataset b. The breeds of the report are sorted by name,
+// and their fields in the order of the BreedInfo fields. The provenance of the breeds is not
+// compared.
+func Diff(a, b BreedInfos) DiffReport {
+	r := DiffReport{Added: []*BreedInfo{}, Removed: []*BreedInfo{}, Changed: []*BreedDiff{}}
+
+	for id, bi := range b {
+		before := a[id]
+		if before == nil {
+			r.Added = append(r.Added, bi)
+			continue
+		}
+
+		if fields := diffBreed(before, bi); len(fields) != 0 {
+			r.Changed = append(r.Changed, &BreedDiff{Id: id, Name: bi.Name, Fields: fields})
+		}
+	}
+
+	for id, bi := range a {
+		if b[id] == nil {
+			r.Removed = append(r.Removed, bi)
+		}
+	}
+
+	sortBreeds(r.Added)
+	sortBreeds(r.Removed)
+
+	sort.Slice(r.Changed, func(i, j int) bool {
+		if r.Changed[i].Name != r.Changed[j].Name {
+			return r.Changed[i].Name < r.Changed[j].Name
+		}
+
+		return r.Changed[i].Id < r.Changed[j].Id
+	})
+
+	return r
+}
+
+func sortBreeds(breeds []*BreedInfo) {
+	sort.Slice(breeds, func(i, j int) bool {
+		if breeds[i].Name != breeds[j].Name {
+			return breeds[i].Name < breeds[j].Name
+		}
+
+		return breeds[i].Id < breeds[j].Id
+	})
+}
+
+func diffBreed(a, b *BreedInfo) (fields []*FieldChange) {
+	scalar := func(field, before, after string) {
+		if before != after {
+			fields = append(fields, &FieldChange{Field: field, Before: before, After: after})
+		}
+	}
+
+	set := func(field string, before, after []string) {
+		if added, removed := diffSet(before, after); len(added) != 0 || len(removed) != 0 {
+			fields = append(fields, &FieldChange{Field: field, Added: added, Removed: removed})
+		}
+	}
+
+	bounds := func(field string, before, after []uint64) {
+		if !reflect.DeepEqual(nonNil(before), nonNil(after)) {
+			fields = append(fields, &FieldChange{Field: field, Before: before, After: after})
+		}
+	}
+
+	scalar("history", a.History, b.History)
+	scalar("type", a.Type, b.Type)
+	scalar("name", a.Name, b.Name)
+	set("size", a.Size, b.Size)
+	set("origins", a.Origin, b.Origin)
+	set("colors", a.Colors, b.Colors)
+	set("images", a.Images, b.Images)
+	bounds("lifeSpan", a.Lifespan, b.Lifespan)
+	bounds("litterSize", a.LitterSize, b.LitterSize)
+	set("temperaments", a.Temperaments, b.Temperaments)
+	set("otherNames", a.OtherNames, b.OtherNames)
+	set("breedGroups", a.BreedGroups, b.BreedGroups)
+
+	chars := make([]string, 0, len(a.BreedChars)+len(b.BreedChars))
+	for char := range a.BreedChars {
+		chars = append(chars, char)
+	}
+
+	for char := range b.BreedChars {
+		if _, exists := a.BreedChars[char]; !exists {
+			chars = append(chars, char)
+		}
+	}
+
+	sort.Strings(chars)
+
+	for _, char := range chars {
+		before, hadBefore := a.BreedChars[char]
+		after, hasAfter := b.BreedChars[char]
+
+		if before != after || hadBefore != hasAfter {
+			change := &FieldChange{Field: "breedChars[" + char + "]"}
+			if hadBefore {
+				change.Before = before
+			}
+
+			if hasAfter {
+				change.After = after
+			}
+
+			fields = append(fields, change)
+		}
+	}
+
+	set("breedRecs", a.BreedRecs, b.BreedRecs)
+	set("refs", refKeys(a.Refs), refKeys(b.Refs))
+
+	if !sameJSON(a.KennelClub, b.KennelClub) {
+		change := &FieldChange{Field: "kennelClub"}
+		if a.KennelClub != nil {
+			change.Before = a.KennelClub
+		}
+
+		if b.KennelClub != nil {
+			change.After = b.KennelClub
+		}
+
+		fields = append(fields, change)
+	}
+
+	return
+}
+
+// diffSet returns the values of after missing from before, and the values of before missing
+// from after, both sorted.
+func diffSet(before, after []string) (added, removed []string) {
+	inBefore := make(map[string]bool, len(before))
+	for _, v := range before {
+		inBefore[v] = true
+	}
+
+	inAfter := make(map[string]bool, len(after))
+	for _, v := range after {
+		inAfter[v] = true
+	}
+
+	for v := range inAfter {
+		if !inBefore[v] {
+			added = append(added, v)
+		}
+	}
+
+	for v := range inBefore {
+		if !inAfter[v] {
+			removed = append(removed, v)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	return
+}
+
+func nonNil(vals []uint64) []uint64 {
+	if vals == nil {
+		return []uint64{}
+	}
+
+	return vals
+}
+
+func refKeys(M map[string]any) []string {
+	res := make([]string, 0, len(M))
+	for key := range M {
+		res = append(res, key)
+	}
+
+	return res
+}
+
+func sameJSON(a, b any) bool {
+	P, errA := json.Marshal(a)
+	Q, errB := json.Marshal(b)
+
+	return errA == nil && errB == nil && string(P) == string(Q)
+}
@@ -0,0 +1,60 @@
+package dogfetch_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rommms07/dogfetch"
+)
+
+func Test_Diff(t *testing.T) {
+	a := dogfetch.BreedInfos{
+		"1": {Id: "1", Name: "Yorkshire Terrier", OtherNames: []string{"Yorkie"}, Colors: []string{"Black", "Tan"},
+			Lifespan: []uint64{13, 16}, BreedChars: map[string]int64{"Adaptability": 5, "Trainability": 4},
+			Images: []string{"a.jpg"}},
+		"2": {Id: "2", Name: "Australian Shepherd"},
+	}
+
+	b := dogfetch.BreedInfos{
+		// The colors are only reordered, and the provenance is not compared.
+		"1": {Id: "1", Name: "Yorkshire Terrier", OtherNames: []string{"Yorkie", "Yorky"}, Colors: []string{"Tan", "Black"},
+			Lifespan: []uint64{12, 15}, BreedChars: map[string]int64{"Adaptability": 4, "Energy": 3},
+			Images: []string{"b.jpg"}, Provenance: map[string][]dogfetch.Provenance{"name": {{URL: "https://example.org"}}}},
+		"3": {Id: "3", Name: "Border Collie"},
+	}
+
+	r := dogfetch.Diff(a, b)
+
+	if len(r.Added) != 1 || r.Added[0].Name != "Border Collie" {
+		t.Errorf("(fail) Expected the Border Collie to be added. (output: %v)", r.Added)
+	}
+
+	if len(r.Removed) != 1 || r.Removed[0].Name != "Australian Shepherd" {
+		t.Errorf("(fail) Expected the Australian Shepherd to be removed. (output: %v)", r.Removed)
+	}
+
+	if len(r.Changed) != 1 || r.Changed[0].Id != "1" {
+		t.Fatalf("(fail) Expected the Yorkshire Terrier to be changed. (output: %v)", r.Changed)
+	}
+
+	expect := []*dogfetch.FieldChange{
+		{Field: "images", Added: []string{"b.jpg"}, Removed: []string{"a.jpg"}},
+		{Field: "lifeSpan", Before: []uint64{13, 16}, After: []uint64{12, 15}},
+		{Field: "otherNames", Added: []string{"Yorky"}},
+		{Field: "breedChars[Adaptability]", Before: int64(5), After: int64(4)},
+		{Field: "breedChars[Energy]", After: int64(3)},
+		{Field: "breedChars[Trainability]", Before: int64(4)},
+	}
+
+	if fields := r.Changed[0].Fields; !reflect.DeepEqual(fields, expect) {
+		for _, fc := range fields {
+			t.Logf("%+v", *fc)
+		}
+
+		t.Errorf("(fail) Did not expect the changed fields of the breed.")
+	}
+
+	if r := dogfetch.Diff(b, b); !r.Empty() {
+		t.Errorf("(fail) Expected no changes between the same datasets. (output: %+v)", r)
+	}
+}
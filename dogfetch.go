@@ -1,9 +1,15 @@
 package dogfetch
 
 import (
+	"context"
 	"sync"
+
+	"github.com/rommms07/dogfetch/internal/utils"
 )
 
+// Version is the version of dogfetch, recorded in the snapshots it saves.
+const Version = utils.Version
+
 type BreedInfo struct {
 	Id           string           `json:"id"`
 	History      string           `json:"history"`
@@ -21,14 +27,25 @@ type BreedInfo struct {
 	BreedChars   map[string]int64 `json:"breedChars"`
 	BreedRecs    []string         `json:"breedRecs"`
 	Refs         map[string]any   `json:"refs"`
-}
+	KennelClub   *KennelClubInfo  `json:"kennelClub,omitempty"`
 
-type Refs = map[string]map[string]string
+	// Provenance maps the json name of each field to the pages its value was taken from.
+	Provenance map[string][]Provenance `json:"provenance,omitempty"`
+}
 
-func init() {
-	fetchDogBreeds()
+// KennelClubInfo is the classification of a breed by The Kennel Club, as published on its
+// breed pages.
+type KennelClubInfo struct {
+	URL                string   `json:"url"`
+	Group              string   `json:"group"`
+	Size               string   `json:"size"`
+	Exercise           string   `json:"exercise"`
+	Grooming           string   `json:"grooming"`
+	StandardHighlights []string `json:"standardHighlights"`
 }
 
+type Refs = map[string]map[string]string
+
 type BreedInfos map[string]*BreedInfo
 
 func (bis BreedInfos) GetByName(name string) (res *BreedInfo) {
@@ -52,14 +69,49 @@ func (bis BreedInfos) GetByName(name string) (res *BreedInfo) {
 	return
 }
 
-func GetById(id string) *BreedInfo {
-	return fetchResult[id]
+var (
+	defaultClient     *Client
+	defaultClientOnce sync.Once
+)
+
+// Default returns the client used by the package level functions. It is created the first
+// time it is needed, and loaded by the package level functions.
+func Default() *Client {
+	defaultClientOnce.Do(func() {
+		defaultClient = New()
+	})
+
+	return defaultClient
+}
+
+func loadDefault() (*Client, error) {
+	c := Default()
+	return c, c.Load(context.Background())
+}
+
+func GetById(id string) (*BreedInfo, error) {
+	c, err := loadDefault()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetById(id)
 }
 
-func GetByName(name string) (res *BreedInfo) {
-	return fetchResult.GetByName(name)
+func GetByName(name string) (*BreedInfo, error) {
+	c, err := loadDefault()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetByName(name)
 }
 
-func GetAll() BreedInfos {
-	return fetchResult
+func GetAll() (BreedInfos, error) {
+	c, err := loadDefault()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetAll(), nil
 }
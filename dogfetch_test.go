@@ -1,8 +1,18 @@
 package dogfetch_test
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/rommms07/dogfetch"
 	"github.com/rommms07/dogfetch/internal/utils"
@@ -72,7 +82,10 @@ var expectedResults = []expTestResults{
 func Test_fetchDogBreeds(t *testing.T) {
 	const EXPECTED_NUM_DOGS = 373
 
-	dogs := dogfetch.FetchDogBreeds()
+	dogs, err := dogfetch.FetchDogBreeds(dogfetch.New(), context.Background())
+	if err != nil {
+		t.Fatalf("(fail) Unable to fetch the dog breeds. (err: %v)", err)
+	}
 
 	if len(dogs) != EXPECTED_NUM_DOGS {
 		t.Errorf("(fail) Expected number of dogs did not matched! (expected: %d)", EXPECTED_NUM_DOGS)
@@ -80,6 +93,9 @@ func Test_fetchDogBreeds(t *testing.T) {
 }
 
 func Test_crawlPage(t *testing.T) {
+	cr := dogfetch.NewCrawler(dogfetch.New())
+	src := &dogfetch.DogBreedsList{}
+
 	for i, T := range testUrls {
 		resUrl, err := url.Parse(T)
 		if err != nil {
@@ -89,15 +105,20 @@ func Test_crawlPage(t *testing.T) {
 		path := resUrl.Path
 		sum := utils.GetMd5Sum(path)
 
-		dogfetch.Queue <- path
-		dogfetch.WGroup.Add(1)
-		go dogfetch.CrawlPage(path)
+		cr.Queue() <- path
+		cr.WGroup().Add(1)
+		go cr.CrawlPage(context.Background(), src, T)
 
-		dogfetch.WGroup.Wait()
+		cr.WGroup().Wait()
 
-		res := dogfetch.FetchResults[sum]
+		res := cr.FetchResults()[sum]
 		expect := expectedResults[i]
 
+		if res == nil {
+			t.Errorf("(fail) Unable to crawl the page: %s", T)
+			continue
+		}
+
 		if res.Name != expect.breedInfo.Name {
 			t.Errorf("(fail) Did not matched the expected dog breed name. (%s != %s)", res.Name,
 				expect.breedInfo.Name)
@@ -119,10 +140,224 @@ func Test_crawlPage(t *testing.T) {
 	}
 }
 
+func Test_New(t *testing.T) {
+	c := dogfetch.New(dogfetch.WithConcurrency(4))
+
+	if len(c.GetAll()) != 0 {
+		t.Errorf("(fail) New should not load any breeds before Load is called.")
+	}
+
+	if bi, err := c.GetById("unknown"); bi != nil || !errors.Is(err, dogfetch.ErrNotFound) {
+		t.Errorf("(fail) Expected ErrNotFound from an unloaded client. (output: %v)", err)
+	}
+
+	if bi, err := c.GetByName("Unknown"); bi != nil || !errors.Is(err, dogfetch.ErrNotFound) {
+		t.Errorf("(fail) Expected ErrNotFound from an unloaded client. (output: %v)", err)
+	}
+}
+
+func Test_Client_Crawl_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := dogfetch.New()
+	dogs, err := c.Crawl(ctx)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("(fail) Expected the crawl to stop with context.Canceled. (output: %v)", err)
+	}
+
+	if len(dogs) != 0 {
+		t.Errorf("(fail) Expected no breeds from a cancelled crawl. (output: %d)", len(dogs))
+	}
+
+	if err := c.Refresh(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("(fail) Expected the refresh to stop with context.Canceled. (output: %v)", err)
+	}
+}
+
+func Test_Client_WithCacheStore(t *testing.T) {
+	srv := newFixtureServer(t, dogBreedsListRoutes)
+	store := dogfetch.NewMemoryStore()
+
+	c := dogfetch.New(dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}), dogfetch.WithCacheStore(store))
+	if _, err := c.Crawl(context.Background()); err != nil {
+		t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+	}
+
+	// The A-Z page, the two breed pages and their references.
+	keys, err := store.List()
+	if err != nil || len(keys) < 3 {
+		t.Errorf("(fail) Did not cache the pages in the given store. (output: %v, err: %v)", keys, err)
+	}
+
+	stats, err := c.PruneCache(0)
+	if err != nil || stats.Entries != len(keys) || stats.Bytes == 0 {
+		t.Errorf("(fail) Did not prune the cache. (output: %+v, err: %v)", stats, err)
+	}
+
+	if keys, _ := store.List(); len(keys) != 0 {
+		t.Errorf("(fail) Expected an empty cache once pruned. (output: %v)", keys)
+	}
+}
+
+func Test_Client_WithCacheDir(t *testing.T) {
+	srv := newFixtureServer(t, dogBreedsListRoutes)
+	dir := filepath.Join(t.TempDir(), "dogfetch")
+
+	// The option wins over the environment.
+	t.Setenv(dogfetch.CacheDirEnv, filepath.Join(t.TempDir(), "env"))
+
+	c := dogfetch.New(dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}), dogfetch.WithCacheDir(dir))
+	if _, err := c.Crawl(context.Background()); err != nil {
+		t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+	}
+
+	if keys, err := dogfetch.NewFileStore(dir).List(); err != nil || len(keys) == 0 {
+		t.Errorf("(fail) Did not cache the pages in the given directory. (output: %v, err: %v)", keys, err)
+	}
+
+	if _, err := os.Stat(os.Getenv(dogfetch.CacheDirEnv)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("(fail) Did not expect the pages to be cached in $%s.", dogfetch.CacheDirEnv)
+	}
+}
+
 func Test_breedInfos_GetByName(t *testing.T) {
 	for _, T := range expectedResults {
-		if dogfetch.FetchResults.GetByName(T.breedInfo.Name).Name != T.breedInfo.Name {
+		bi, err := dogfetch.GetByName(T.breedInfo.Name)
+		if err != nil {
+			t.Errorf("(fail) Unable to get the dog breed. (input: %v, err: %v)", T.breedInfo.Name, err)
+			continue
+		}
+
+		if bi.Name != T.breedInfo.Name {
 			t.Errorf("(fail) Did not matched expected dog breed name! (input: %v)", T.breedInfo.Name)
 		}
 	}
 }
+
+// timingTransport records when each request is made.
+type timingTransport struct {
+	mu    sync.Mutex
+	times []time.Time
+}
+
+func (tt *timingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tt.mu.Lock()
+	tt.times = append(tt.times, time.Now())
+	tt.mu.Unlock()
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func Test_Client_WithRateLimit(t *testing.T) {
+	srv := newFixtureServer(t, dogBreedsListRoutes)
+	tt := &timingTransport{}
+
+	c := newTestClient(t, dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}),
+		dogfetch.WithHTTPClient(&http.Client{Transport: tt}), dogfetch.WithRateLimit(20, 1))
+
+	if _, err := c.Crawl(context.Background()); err != nil {
+		t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+	}
+
+	// The references are on the host of the source, so every request is limited to 20 per
+	// second.
+	if len(tt.times) < 3 {
+		t.Fatalf("(fail) Expected the pages to be fetched. (output: %d requests)", len(tt.times))
+	}
+
+	sort.Slice(tt.times, func(i, j int) bool { return tt.times[i].Before(tt.times[j]) })
+
+	span := tt.times[len(tt.times)-1].Sub(tt.times[0])
+	if least := time.Duration(len(tt.times)-1) * 40 * time.Millisecond; span < least {
+		t.Errorf("(fail) Expected the requests to be spaced by the rate limit. (output: %d requests in %v)", len(tt.times), span)
+	}
+}
+
+// headerTransport records the headers of each request.
+type headerTransport struct {
+	mu      sync.Mutex
+	headers []http.Header
+}
+
+func (ht *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ht.mu.Lock()
+	ht.headers = append(ht.headers, req.Header.Clone())
+	ht.mu.Unlock()
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func Test_Client_WithUserAgent(t *testing.T) {
+	srv := newFixtureServer(t, dogBreedsListRoutes)
+
+	tests := []struct {
+		opts      []dogfetch.Option
+		userAgent string
+		team      string
+	}{
+		{nil, dogfetch.DefaultUserAgent, ""},
+		{[]dogfetch.Option{dogfetch.WithUserAgent("tester/1.0"), dogfetch.WithHeader("X-Team", "dogs")}, "tester/1.0", "dogs"},
+	}
+
+	for _, T := range tests {
+		ht := &headerTransport{}
+		opts := append([]dogfetch.Option{dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}), dogfetch.WithTransport(ht)}, T.opts...)
+
+		if _, err := newTestClient(t, opts...).Crawl(context.Background()); err != nil {
+			t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+		}
+
+		if len(ht.headers) == 0 {
+			t.Fatalf("(fail) Expected the pages to be fetched through the transport.")
+		}
+
+		for _, hdr := range ht.headers {
+			if hdr.Get("User-Agent") != T.userAgent || hdr.Get("X-Team") != T.team {
+				t.Errorf("(fail) Did not expect the headers of the request. (output: %v)", hdr)
+				break
+			}
+		}
+	}
+}
+
+func Test_Client_WithProxy(t *testing.T) {
+	srv := newFixtureServer(t, dogBreedsListRoutes)
+
+	var mu sync.Mutex
+	var proxied []string
+
+	// The proxy forwards the requests it is given, which name the url they are made to.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.String())
+		mu.Unlock()
+
+		req, _ := http.NewRequestWithContext(r.Context(), r.Method, r.URL.String(), nil)
+		res, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		defer res.Body.Close()
+
+		w.WriteHeader(res.StatusCode)
+		io.Copy(w, res.Body)
+	}))
+	defer proxy.Close()
+
+	proxyUrl, _ := url.Parse(proxy.URL)
+
+	c := newTestClient(t, dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}),
+		dogfetch.WithProxy(proxyUrl), dogfetch.WithTimeout(5*time.Second))
+
+	if _, err := c.Crawl(context.Background()); err != nil {
+		t.Fatalf("(fail) Unable to crawl the fixture pages through the proxy. (err: %v)", err)
+	}
+
+	if len(proxied) < 3 || proxied[0] != srv.URL+"/robots.txt" {
+		t.Errorf("(fail) Expected the pages to be fetched through the proxy. (output: %v)", proxied)
+	}
+}
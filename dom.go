@@ -0,0 +1,105 @@
+package dogfetch
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// findAll returns the elements under n, in document order, for which match returns true.
+func findAll(n *html.Node, match func(*html.Node) bool) (nodes []*html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && match(c) {
+			nodes = append(nodes, c)
+		}
+
+		nodes = append(nodes, findAll(c, match)...)
+	}
+
+	return
+}
+
+// find returns the first element under n for which match returns true, or nil.
+func find(n *html.Node, match func(*html.Node) bool) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && match(c) {
+			return c
+		}
+
+		if found := find(c, match); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// next returns the first element following n in document order, outside of n, for which
+// match returns true, or nil.
+func next(n *html.Node, match func(*html.Node) bool) *html.Node {
+	for ; n != nil; n = n.Parent {
+		for s := n.NextSibling; s != nil; s = s.NextSibling {
+			if s.Type == html.ElementNode && match(s) {
+				return s
+			}
+
+			if found := find(s, match); found != nil {
+				return found
+			}
+		}
+	}
+
+	return nil
+}
+
+func isTag(tag string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return n.Data == tag
+	}
+}
+
+func hasClass(tag, class string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		if len(tag) != 0 && n.Data != tag {
+			return false
+		}
+
+		for _, c := range strings.Fields(attr(n, "class")) {
+			if c == class {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+// nodeText returns the text content of n with its whitespace collapsed.
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
@@ -0,0 +1,62 @@
+package dogfetch
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+)
+
+//go:generate go run ./cmd snapshot -o data/breeds.snapshot
+
+// embeddedData holds the snapshot of the breeds shipped within the package, for the programs
+// which cannot reach the sources. It is written by go generate from a crawl of the sources,
+// and the package is built without it until then.
+//
+//go:embed data
+var embeddedData embed.FS
+
+// embeddedFS is where EmbeddedSnapshot reads the snapshot from, the tests replacing it.
+var embeddedFS fs.FS = embeddedData
+
+const embeddedSnapshotPath = "data/" + snapshotFile
+
+// EmbeddedSnapshot returns the snapshot of the breeds shipped within the package, or
+// ErrNoEmbeddedSnapshot if it was built without one.
+func EmbeddedSnapshot() (*Snapshot, error) {
+	f, err := embeddedFS.Open(embeddedSnapshotPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoEmbeddedSnapshot
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return ReadSnapshot(f)
+}
+
+// LoadMode tells Load where the dataset of a client comes from.
+type LoadMode int
+
+const (
+	// LoadLive loads the snapshot saved by a previous crawl, or else crawls the sources. It is
+	// the default mode.
+	LoadLive LoadMode = iota
+
+	// LoadEmbedded only loads the embedded snapshot, nothing is ever fetched. Load returns
+	// ErrNoEmbeddedSnapshot if the package was built without one.
+	LoadEmbedded
+
+	// LoadEmbeddedThenRefresh starts from the snapshot saved by a previous crawl, or else from
+	// the embedded one, then refreshes the dataset by crawling the sources. The dataset can be
+	// looked up while it is refreshed, and is kept if the refresh fails. Without either, it
+	// only crawls the sources.
+	LoadEmbeddedThenRefresh
+)
+
+// WithLoadMode sets where Load takes the dataset of the client from, LoadLive by default.
+func WithLoadMode(mode LoadMode) Option {
+	return func(c *Client) {
+		c.loadMode = mode
+	}
+}
@@ -0,0 +1,176 @@
+package dogfetch_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rommms07/dogfetch"
+)
+
+// testEmbeddedSnapshot is built from the fixtures, and stands in for the embedded snapshot. It
+// is kept at schema 1, as it was written, so reading it also goes through the migrations.
+const testEmbeddedSnapshot = "testdata/embedded/breeds.snapshot"
+
+// minEmbeddedBreeds is the least number of breeds expected from a crawl of dogbreedslist.info,
+// which lists a few hundred of them.
+const minEmbeddedBreeds = 250
+
+func Test_EmbeddedSnapshot(t *testing.T) {
+	s, err := dogfetch.EmbeddedSnapshot()
+	if errors.Is(err, dogfetch.ErrNoEmbeddedSnapshot) {
+		t.Skip("No embedded snapshot was generated yet, see data/README.md.")
+	} else if err != nil {
+		t.Fatalf("(fail) Unable to read the embedded snapshot. (err: %v)", err)
+	}
+
+	if s.Header.Schema != dogfetch.SnapshotSchema || len(s.Header.Sources) == 0 || len(s.Breeds) < minEmbeddedBreeds {
+		t.Errorf("(fail) Expected a snapshot of a full crawl. (output: %+v, %d breeds)", s.Header, len(s.Breeds))
+	}
+
+	for _, name := range []string{"Yorkshire Terrier", "Golden Retriever", "Border Collie"} {
+		if s.Breeds.GetByName(name) == nil {
+			t.Errorf("(fail) Expected the embedded snapshot to hold the breed: %s", name)
+		}
+	}
+}
+
+func Test_EmbeddedSnapshot_missing(t *testing.T) {
+	dogfetch.UseEmbeddedSnapshot(t, "")
+
+	if _, err := dogfetch.EmbeddedSnapshot(); !errors.Is(err, dogfetch.ErrNoEmbeddedSnapshot) {
+		t.Errorf("(fail) Expected ErrNoEmbeddedSnapshot. (output: %v)", err)
+	}
+
+	c := dogfetch.New(dogfetch.WithCacheStore(dogfetch.NewMemoryStore()), dogfetch.WithLoadMode(dogfetch.LoadEmbedded))
+	if err := c.Load(context.Background()); !errors.Is(err, dogfetch.ErrNoEmbeddedSnapshot) {
+		t.Errorf("(fail) Expected ErrNoEmbeddedSnapshot from Load. (output: %v)", err)
+	}
+}
+
+func Test_Client_LoadEmbedded(t *testing.T) {
+	dogfetch.UseEmbeddedSnapshot(t, testEmbeddedSnapshot)
+
+	// Nothing is fetched, so the sources cannot be reached.
+	c := dogfetch.New(dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: "http://127.0.0.1:0"}),
+		dogfetch.WithCacheStore(dogfetch.NewMemoryStore()), dogfetch.WithLoadMode(dogfetch.LoadEmbedded))
+
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatalf("(fail) Unable to load the embedded snapshot. (err: %v)", err)
+	}
+
+	if bi, err := c.GetByName("Yorkshire Terrier"); err != nil || len(bi.OtherNames) == 0 {
+		t.Errorf("(fail) Did not load the embedded breeds. (output: %+v, err: %v)", bi, err)
+	}
+}
+
+func Test_Client_LoadEmbeddedThenRefresh(t *testing.T) {
+	dogfetch.UseEmbeddedSnapshot(t, testEmbeddedSnapshot)
+	embedded, err := dogfetch.EmbeddedSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("offline", func(t *testing.T) {
+		c := dogfetch.New(dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: "http://127.0.0.1:0"}),
+			dogfetch.WithCacheStore(dogfetch.NewMemoryStore()), dogfetch.WithLoadMode(dogfetch.LoadEmbeddedThenRefresh),
+			dogfetch.WithRetryPolicy(dogfetch.RetryPolicy{Attempts: 1}))
+
+		if err := c.Load(context.Background()); !errors.Is(err, dogfetch.ErrSourceUnavailable) {
+			t.Errorf("(fail) Expected ErrSourceUnavailable from the refresh. (output: %v)", err)
+		}
+
+		if len(c.GetAll()) != len(embedded.Breeds) {
+			t.Errorf("(fail) Expected the embedded breeds to be kept. (output: %d breeds)", len(c.GetAll()))
+		}
+
+		// The client is loaded, the refresh is not attempted again.
+		if err := c.Load(context.Background()); err != nil {
+			t.Errorf("(fail) Did not expect an already loaded client to be loaded again. (err: %v)", err)
+		}
+	})
+
+	t.Run("online", func(t *testing.T) {
+		srv := newFixtureServer(t, dogBreedsListRoutes)
+
+		c := newTestClient(t, dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}), dogfetch.WithLoadMode(dogfetch.LoadEmbeddedThenRefresh))
+		if err := c.Load(context.Background()); err != nil {
+			t.Fatalf("(fail) Unable to refresh the embedded breeds. (err: %v)", err)
+		}
+
+		bi, err := c.GetByName("Yorkshire Terrier")
+		if err != nil {
+			t.Fatalf("(fail) Unable to get the refreshed breed. (err: %v)", err)
+		}
+
+		if bi.Provenance["name"][0].URL != srv.URL+"/all-dog-breeds/yorkshire-terrier.html" {
+			t.Errorf("(fail) Expected the breed to come from the crawl. (output: %v)", bi.Provenance["name"])
+		}
+	})
+}
+
+func Test_Client_LoadLive_sourcesDown(t *testing.T) {
+	srv := newFixtureServer(t, dogBreedsListRoutes)
+	srv.Close()
+
+	newClient := func() *dogfetch.Client {
+		return dogfetch.New(dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}),
+			dogfetch.WithCacheStore(dogfetch.NewMemoryStore()), dogfetch.WithRetryPolicy(dogfetch.RetryPolicy{Attempts: 1}))
+	}
+
+	t.Run("embedded", func(t *testing.T) {
+		dogfetch.UseEmbeddedSnapshot(t, testEmbeddedSnapshot)
+
+		// The failure of the crawl is still returned.
+		c := newClient()
+		err := c.Load(context.Background())
+
+		var crawlErr *dogfetch.CrawlError
+		if !errors.As(err, &crawlErr) || !crawlErr.Unreachable || !errors.Is(err, dogfetch.ErrSourceUnavailable) {
+			t.Errorf("(fail) Expected the unreachable sources along with the embedded breeds. (output: %v)", err)
+		}
+
+		if bi, err := c.GetByName("Yorkshire Terrier"); err != nil {
+			t.Errorf("(fail) Did not load the embedded breeds. (output: %+v, err: %v)", bi, err)
+		}
+	})
+
+	t.Run("without embedded", func(t *testing.T) {
+		dogfetch.UseEmbeddedSnapshot(t, "")
+
+		c := newClient()
+		if err := c.Load(context.Background()); !errors.Is(err, dogfetch.ErrSourceUnavailable) {
+			t.Errorf("(fail) Expected ErrSourceUnavailable without an embedded snapshot. (output: %v)", err)
+		}
+
+		if len(c.GetAll()) != 0 {
+			t.Errorf("(fail) Did not expect any breed to be loaded. (output: %d breeds)", len(c.GetAll()))
+		}
+	})
+}
+
+func Test_Client_LoadLive_partialFailure(t *testing.T) {
+	dogfetch.UseEmbeddedSnapshot(t, testEmbeddedSnapshot)
+
+	// The Yorkshire Terrier page is missing, while the source is reachable.
+	routes := make(map[string]string)
+	for path, fixture := range dogBreedsListRoutes {
+		routes[path] = fixture
+	}
+
+	delete(routes, "/all-dog-breeds/yorkshire-terrier.html")
+	srv := newFixtureServer(t, routes)
+
+	c := newTestClient(t, dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}), dogfetch.WithRetryPolicy(dogfetch.RetryPolicy{Attempts: 1}))
+
+	err := c.Load(context.Background())
+
+	var crawlErr *dogfetch.CrawlError
+	if !errors.As(err, &crawlErr) || crawlErr.Unreachable || len(crawlErr.Errors) != 1 {
+		t.Fatalf("(fail) Expected the failed page to be reported. (output: %v)", err)
+	}
+
+	if len(c.GetAll()) != 0 {
+		t.Errorf("(fail) Did not expect the embedded breeds in place of the crawl. (output: %d breeds)", len(c.GetAll()))
+	}
+}
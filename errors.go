@@ -0,0 +1,107 @@
+package dogfetch
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rommms07/dogfetch/internal/utils"
+)
+
+var (
+	// ErrNotFound is returned when no breed matches a lookup.
+	ErrNotFound = errors.New("breed not found")
+
+	// ErrSourceUnavailable is reported when a page cannot be fetched from its source.
+	ErrSourceUnavailable = utils.ErrSourceUnavailable
+
+	// ErrParse is reported when a fetched page does not contain the expected breed data.
+	ErrParse = errors.New("cannot parse page")
+
+	// ErrCacheCorrupt is reported when a cached page or a saved dataset cannot be read back.
+	ErrCacheCorrupt = utils.ErrCacheCorrupt
+
+	// ErrNoEmbeddedSnapshot is returned when the package was built without the snapshot
+	// written by go generate.
+	ErrNoEmbeddedSnapshot = errors.New("no embedded snapshot")
+
+	// ErrBlocked is reported when the robots.txt of a host disallows fetching a page.
+	ErrBlocked = errors.New("disallowed by robots.txt")
+)
+
+// Error describes an operation on a page which failed. Its Kind is one of the errors above,
+// so it can be matched with errors.Is, while errors.As gives access to the URL of the page.
+type Error = utils.Error
+
+// RetryPolicy tells how the requests failed by a network error, a 5xx or a 429 response are
+// retried, with an exponential backoff and a random jitter. The delay asked by the
+// Retry-After header of a response is respected. The zero fields take the value of
+// DefaultRetryPolicy, so a policy of a single attempt disables the retries.
+type RetryPolicy = utils.RetryPolicy
+
+// DefaultRetryPolicy makes up to 4 attempts, waiting from 500ms up to 30s between them.
+var DefaultRetryPolicy = utils.DefaultRetryPolicy
+
+// HostError is reported once for a host which cannot be crawled at all, such as when its
+// robots.txt cannot be fetched. Its pages are not reported on their own.
+type HostError struct {
+	Host string
+	Err  error
+}
+
+func (e *HostError) Error() string {
+	return fmt.Sprintf("host unavailable: %s (err: %v)", e.Host, e.Err)
+}
+
+func (e *HostError) Unwrap() error {
+	return e.Err
+}
+
+// CrawlError is returned by a crawl when some of the breed pages, or some of the hosts, could
+// not be crawled. The breeds of the remaining pages are still returned along with it.
+type CrawlError struct {
+	Errors []error
+
+	// Unreachable tells that none of the sources could be reached, so not a single breed page
+	// was discovered.
+	Unreachable bool
+}
+
+func (e *CrawlError) Error() string {
+	var pages, hosts []string
+	for _, err := range e.Errors {
+		var hostErr *HostError
+		if errors.As(err, &hostErr) {
+			hosts = append(hosts, err.Error())
+		} else {
+			pages = append(pages, err.Error())
+		}
+	}
+
+	var msgs []string
+	if len(pages) != 0 {
+		msgs = append(msgs, fmt.Sprintf("%d pages failed to crawl: %s", len(pages), strings.Join(pages, "; ")))
+	}
+
+	return strings.Join(append(msgs, hosts...), "; ")
+}
+
+func (e *CrawlError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *CrawlError) As(target any) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
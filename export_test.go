@@ -1,17 +1,103 @@
 package dogfetch
 
+import (
+	"context"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// Crawler exposes the unexported crawler to the test code, so that a single breed page can be
+// crawled without running the whole crawl.
+type Crawler = crawler
+
 var (
-	// Since the sync.WaitGroup object in init.go is defined by value, we cannot modify
-	// its state directly from the test code. So we assign its reference value into this
-	// test exported identifier to refer to it later in the test.
-	WGroup       = &wg
-	Queue        = queue
-	FetchResults = fetchResult
-
-	FetchDogBreeds = fetchDogBreeds
-
-	// crawlPage is tightly cooupled with the digPage function, so if we are testing
-	// this unexported we may in turn also be testing the digPage function. Hence it is not
-	// necessary to export the digPage function for testing because of this relationship.
-	CrawlPage = crawlPage
+	FetchDogBreeds = func(c *Client, ctx context.Context) (BreedInfos, error) {
+		s, err := c.fetchDogBreeds(ctx)
+		if s == nil {
+			return nil, err
+		}
+
+		return s.Breeds, err
+	}
+
+	NewCrawler = newCrawler
+
+	// RobotsAllowed tells whether the robots.txt P allows agent to fetch path.
+	RobotsAllowed = func(P []byte, agent, path string) bool {
+		return parseRobots(P, agent).allowed(path)
+	}
+
+	// RobotsDelay returns the Crawl-delay the robots.txt P asks from agent.
+	RobotsDelay = func(P []byte, agent string) time.Duration {
+		return parseRobots(P, agent).delay
+	}
 )
+
+// UseEmbeddedSnapshot makes EmbeddedSnapshot read the snapshot at path until the test ends,
+// or find none when path is empty.
+func UseEmbeddedSnapshot(t testing.TB, path string) {
+	fsys := fstest.MapFS{}
+	if len(path) != 0 {
+		P, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fsys[embeddedSnapshotPath] = &fstest.MapFile{Data: P}
+	}
+
+	prev := embeddedFS
+	embeddedFS = fsys
+	t.Cleanup(func() { embeddedFS = prev })
+}
+
+// RobotsExpiry returns how long the robots.txt rules of the host of rawUrl are still kept by
+// the client, or zero if it has none.
+func (c *Client) RobotsExpiry(rawUrl string) time.Duration {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return 0
+	}
+
+	h := c.robots.host(u)
+
+	c.robots.mu.Lock()
+	defer c.robots.mu.Unlock()
+
+	if h.expires.IsZero() {
+		return 0
+	}
+
+	return time.Until(h.expires)
+}
+
+// Since the sync.WaitGroup object of the crawler is defined by value, we cannot modify
+// its state directly from the test code. So we return its reference value to refer to it
+// later in the test.
+func (cr *crawler) WGroup() *sync.WaitGroup { return &cr.wg }
+
+func (cr *crawler) Queue() chan string { return cr.queue }
+
+// FetchResults returns the breeds crawled so far, before they are merged together.
+func (cr *crawler) FetchResults() BreedInfos {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	res := make(BreedInfos)
+	for _, r := range cr.records {
+		res[r.breed.Id] = r.breed
+	}
+
+	return res
+}
+
+// crawlPage is tightly cooupled with the digPage function, so if we are testing
+// this unexported we may in turn also be testing the digPage function. Hence it is not
+// necessary to export the digPage function for testing because of this relationship.
+func (cr *crawler) CrawlPage(ctx context.Context, src Source, pageUrl string) {
+	cr.crawlPage(ctx, src, pageUrl)
+}
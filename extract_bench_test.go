@@ -0,0 +1,193 @@
+package dogfetch
+
+import (
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rommms07/dogfetch/internal/utils"
+)
+
+// The number of breed pages listed on the A-Z page of dogbreedslist.info, every iteration of
+// the benchmarks below extracts as many pages.
+const benchCrawlSize = 373
+
+func BenchmarkDigPage(b *testing.B) {
+	var pages [][]byte
+	for _, fixture := range []string{"australian-shepherd", "yorkshire-terrier"} {
+		P, err := ioutil.ReadFile("testdata/dogbreedslist/" + fixture + ".html")
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		pages = append(pages, P)
+	}
+
+	src := &DogBreedsList{}
+
+	b.Run("regexp", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < benchCrawlSize; j++ {
+				if _, err := legacyDigPage(src, pages[j%len(pages)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("dom", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < benchCrawlSize; j++ {
+				if _, err := src.digPage(pages[j%len(pages)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+// legacyDigPage is the regexp based extractor digPage replaced, it is kept to compare the
+// two in the benchmark.
+func legacyDigPage(s *DogBreedsList, P []byte) (bi *BreedInfo, err error) {
+	bi = &BreedInfo{
+		BreedChars: make(map[string]int64),
+		Refs:       make(map[string]any),
+	}
+
+	mainFmt := `(?m)(?s)<div class="content">(.|\n)*?`
+	namePatt := regexp.MustCompile(mainFmt + `<h1>(?P<name>[^<]+)<\/h1>`)
+	imagesPatt := regexp.MustCompile(mainFmt + `<div class="slideshow">(?P<images>.+?)<table class="table-03">.*?`)
+	refsPatt := regexp.MustCompile(mainFmt + `(<div class="like">|<h3>References</h3>)(?P<url>.+)<\/ul>`)
+	otherNamesPatt := regexp.MustCompile(mainFmt + `<td>Other names<\/td>.*?<td>(?P<otherNames>[^<]+?)<\/td>`)
+	breedGroupsPatt := regexp.MustCompile(mainFmt + `<td>Breed Group<\/td>.*?<td>(?P<breedGroups>.+?)<\/td>`)
+	originPatt := regexp.MustCompile(mainFmt + `<td>Origin<\/td>.*?<td class\="flag">(?P<origin>.+?)(<\/td>)`)
+	sizePatt := regexp.MustCompile(mainFmt + `<td>Size</td>.*?<td>(?P<size>.+?)<\/td>`)
+	tempPatt := regexp.MustCompile(mainFmt + `<td>Temperament<\/td>.*?<td>*(?P<temperaments>.+?)<\/td>`)
+	colorsPatt := regexp.MustCompile(mainFmt + `<td>Colors<\/td>.*?<td>*(?P<colors>.+?)<\/td>`)
+	typePatt := regexp.MustCompile(mainFmt + `<td>Type<\/td>.*?<td>(?P<type>.+?)<\/td>`)
+	charsPatt := regexp.MustCompile(mainFmt + `<table class="table-02">.*?<tbody>.*Breed Characteristics.*?(?P<chars>.+?)<\/tbody>.*?<\/table>`)
+	lspanPatt := regexp.MustCompile(mainFmt + `<td>Life span<\/td>.*?<td>(?P<start>[\d]+?)-(?P<end>[\d]+?).+?<\/td>`)
+	litterSizePatt := regexp.MustCompile(mainFmt + `<td>Litter Size<\/td>.*?<td>(?P<start>[\d]+?)-(?P<end>[\d]+?).+?<\/td>`)
+	historyPatt := regexp.MustCompile(mainFmt + `<h2>History<\/h2>.*?<td>.*?<p>(?P<history>.+?)<\/p>`)
+
+	indices := namePatt.FindSubmatchIndex(P)
+	bi.Name = string(namePatt.Expand([]byte{}, []byte(`$name`), P, indices))
+
+	if len(strings.TrimSpace(bi.Name)) == 0 {
+		return nil, errors.New("no breed name on the page")
+	}
+
+	indices = typePatt.FindSubmatchIndex(P)
+	bi.Type = string(typePatt.Expand([]byte{}, []byte(`$type`), P, indices))
+
+	indices = refsPatt.FindSubmatchIndex(P)
+	refs := refsPatt.Expand([]byte{}, []byte(`$url`), P, indices)
+	hrefPatt := regexp.MustCompile(`href="(?P<url>.+?)"`)
+	urls := []string{}
+	for _, indices := range hrefPatt.FindAllSubmatchIndex(refs, -1) {
+		href := string(hrefPatt.Expand([]byte{}, []byte(`$url`), refs, indices))
+
+		if strings.Contains(href, "//") {
+			href = regexp.MustCompile(`^//`).ReplaceAllString(href, "https://")
+			urls = append(urls, href)
+			continue
+		}
+
+		urls = append(urls, s.base()+href)
+		bi.BreedRecs = append(bi.BreedRecs, utils.GetMd5Sum(href))
+	}
+
+	// The references are fetched by the crawler once the page is extracted.
+	for _, href := range urls {
+		bi.Refs[href] = nil
+	}
+
+	for _, indices := range otherNamesPatt.FindAllSubmatchIndex(P, -1) {
+		otherNames := string(otherNamesPatt.Expand([]byte{}, []byte(`$otherNames`), P, indices))
+		bi.OtherNames = append(bi.OtherNames, strings.Split(otherNames, ",")...)
+		bi.OtherNames = cleanStrings(bi.OtherNames)
+	}
+
+	bi.OtherNames = uniqueSet(bi.OtherNames)
+
+	bi.Origin = getResults(originPatt, "</p>", []byte(`$origin`), P)
+	bi.BreedGroups = getResults(breedGroupsPatt, "</p>", []byte(`$breedGroups`), P)
+	bi.Size = getResults(sizePatt, "to", []byte(`$size`), P)
+	bi.Temperaments = getResults(tempPatt, "</p>", []byte(`$temperaments`), P)
+	bi.Colors = func() []string {
+		results := getResults(colorsPatt, "</p>", []byte(`$colors`), P)
+		maps := make(map[string]int)
+		for _, val := range results {
+			maps[val] = 1
+		}
+
+		results = make([]string, 0)
+
+		for k := range maps {
+			results = append(results, k)
+		}
+
+		return results
+	}()
+
+	indices = charsPatt.FindSubmatchIndex(P)
+	chars := charsPatt.Expand([]byte{}, []byte(`$chars`), P, indices)
+	charsTypePatt := regexp.MustCompile(`(?m)<td>(?P<type>[A-Za-z ]*?)</td>(.|\n)*?<p class="star-0\d">(?P<score>\d) stars<\/p>`)
+
+	for _, indices := range charsTypePatt.FindAllSubmatchIndex(chars, -1) {
+		chars := strings.Split(string(charsTypePatt.Expand([]byte{}, []byte(`$type,$score`), chars, indices)), ",")
+
+		if len(chars[0]) == 0 {
+			continue
+		}
+
+		score, err := strconv.ParseInt(chars[1], 10, 64)
+		if err != nil {
+			score = 0
+		}
+
+		bi.BreedChars[strings.TrimSpace(chars[0])] = score
+	}
+
+	indices = imagesPatt.FindSubmatchIndex(P)
+	images := imagesPatt.Expand([]byte{}, []byte(`$images`), P, indices)
+	srcPatt := regexp.MustCompile(`<img.*?src="(?P<imageSrc>\/uploads\/dog-pictures\/[^"]+)"`)
+
+	for _, indices := range srcPatt.FindAllSubmatchIndex(images, -1) {
+		src := string(srcPatt.Expand([]byte{}, []byte(`$imageSrc`), images, indices))
+		bi.Images = append(bi.Images, s.base()+src)
+	}
+
+	indices = lspanPatt.FindSubmatchIndex(P)
+	lifespan := lspanPatt.Expand([]byte{}, []byte(`$start-$end`), P, indices)
+
+	for _, years := range strings.Split(string(lifespan), "-") {
+		y, _ := strconv.ParseUint(years, 10, 64)
+		bi.Lifespan = append(bi.Lifespan, y)
+	}
+
+	indices = litterSizePatt.FindSubmatchIndex(P)
+	litterSize := litterSizePatt.Expand([]byte{}, []byte(`$start-$end`), P, indices)
+
+	for _, litter := range strings.Split(string(litterSize), "-") {
+		l, _ := strconv.ParseUint(litter, 10, 64)
+		bi.LitterSize = append(bi.LitterSize, l)
+	}
+
+	indices = historyPatt.FindSubmatchIndex(P)
+	history := historyPatt.Expand([]byte{}, []byte(`$history`), P, indices)
+
+	bi.History = string(history)
+
+	return
+}
+
+func getResults(patt *regexp.Regexp, sep string, tmp, P []byte) []string {
+	indices := patt.FindSubmatchIndex(P)
+	results := string(patt.Expand([]byte{}, []byte(tmp), P, indices))
+	results = removeMisc(results)
+	return cleanResults(strings.Split(results, sep))
+}
@@ -0,0 +1,70 @@
+package dogfetch_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rommms07/dogfetch"
+)
+
+// newFixtureServer serves the recorded pages found in testdata. The routes map the path of a
+// request, with its query when there is one, to the fixture file served for it. Every
+// {{server}} found in a fixture is replaced by the url of the server, so the pages can link
+// to each other.
+func newFixtureServer(t *testing.T, routes map[string]string) *httptest.Server {
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fixture, exists := routes[r.URL.RequestURI()]
+		if !exists {
+			fixture, exists = routes[r.URL.Path]
+		}
+
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+
+		P, err := ioutil.ReadFile("testdata/" + fixture)
+		if err != nil {
+			t.Errorf("(fail) Unable to read the fixture: %s (err: %v)", fixture, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte(strings.ReplaceAll(string(P), "{{server}}", srv.URL)))
+	}))
+
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+var dogBreedsListRoutes = map[string]string{
+	"/dog-breeds-a-z/":                         "dogbreedslist/dog-breeds-a-z.html",
+	"/all-dog-breeds/australian-shepherd.html": "dogbreedslist/australian-shepherd.html",
+	"/all-dog-breeds/yorkshire-terrier.html":   "dogbreedslist/yorkshire-terrier.html",
+	"/refs/australian-shepherd.html":           "refs/australian-shepherd.html",
+	"/refs/yorkshire-terrier.html":             "refs/yorkshire-terrier.html",
+}
+
+var infoDogsRoutes = map[string]string{
+	"/search/":                      "infodogs/search.html",
+	"/search/?page=2&sort=name":     "infodogs/search-2.html",
+	"/breeds/border-collie/":        "infodogs/border-collie.html",
+	"/breeds/yorkshire-terrier/":    "infodogs/yorkshire-terrier.html",
+	"/breeds/welsh-corgi-pembroke/": "infodogs/welsh-corgi-pembroke.html",
+}
+
+var kennelClubRoutes = map[string]string{
+	"/search/content-search/":                              "kennelclub/content-search.html",
+	"/search/breeds-a-to-z/breeds/pastoral/border-collie/": "kennelclub/border-collie.html",
+	"/search/breeds-a-to-z/breeds/toy/yorkshire-terrier/":  "kennelclub/yorkshire-terrier.html",
+}
+
+// newTestClient returns a client which keeps its cache in a temporary directory.
+func newTestClient(t *testing.T, opts ...dogfetch.Option) *dogfetch.Client {
+	return dogfetch.New(append([]dogfetch.Option{dogfetch.WithCacheStore(dogfetch.NewFileStore(t.TempDir()))}, opts...)...)
+}
@@ -1,3 +1,5 @@
 module github.com/rommms07/dogfetch
 
 go 1.18
+
+require golang.org/x/net v0.21.0
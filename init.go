@@ -1,243 +1,343 @@
 package dogfetch
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"log"
 	URL "net/url"
 	"regexp"
-	"strconv"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rommms07/dogfetch/internal/utils"
 )
 
-var (
+// crawler holds the state of a single crawl. The number of breed pages crawled in parallel,
+// and of references fetched in parallel, are limited by utilizing the properties of bufferred
+// channels.
+type crawler struct {
 	mu sync.Mutex
 	wg sync.WaitGroup
 
-	// Usually making a series of concurrent HTTP request into the server causes an Internal Server Error
-	// on the server, to avoid that issue, we limit the number of concurrent request up to N by utilizing the
-	// properties of bufferred channels. In this case we limit the number of parallel HTTP request by
-	// 50.
-	queue       = make(chan string, 50)
-	fetchResult = make(BreedInfos)
-)
-
-func fetchDogBreeds() (dogs map[string]*BreedInfo) {
-	if P, err := ioutil.ReadFile("/tmp/breeds.json"); err == nil {
-		err := json.Unmarshal(P, &fetchResult)
-		if err != nil {
-			log.Fatal(err)
-		}
+	cache       *utils.Cache
+	sources     []Source
+	priority    []BreedSource
+	queue       chan string
+	refQueue    chan struct{}
+	records     []*record
+	fetchResult BreedInfos
+	errs        []error
+	robots      *robotsCache
+	blocked     map[string]bool
+	unmatched   []string
+	unavailable map[string]bool
+	report      func(*CrawlReport)
+	emit        func(Event)
+
+	// prev are the pages of the previous snapshot, whose breeds are kept as long as the pages
+	// are unchanged. Every page is extracted when it is nil.
+	prev map[string]*SnapshotPage
+}
 
-		dogs = fetchResult
-		return
+func newCrawler(c *Client) *crawler {
+	return &crawler{
+		cache:       c.cache,
+		sources:     c.sources,
+		priority:    c.sourcePriority(),
+		queue:       make(chan string, c.concurrency),
+		refQueue:    make(chan struct{}, c.refConc),
+		fetchResult: make(BreedInfos),
+		robots:      c.robots,
+		blocked:     make(map[string]bool),
+		unavailable: make(map[string]bool),
+		report:      c.setLastCrawl,
+		emit:        c.emit,
 	}
+}
 
-	const (
-		listBreeds = string(Source1) + "/dog-breeds-a-z/"
-		s2Search   = string(Source2) + "/search/"
-		s3Search   = string(Source3) + "/search/content-search"
-	)
+// fetchDogBreeds returns the saved snapshot of the client, or else crawls the breeds and
+// saves them as its snapshot. A crawled snapshot is still returned if it cannot be saved.
+func (c *Client) fetchDogBreeds(ctx context.Context) (*Snapshot, error) {
+	if s, err := c.savedSnapshot(); err != nil {
+		return nil, err
+	} else if s != nil {
+		return s, nil
+	}
 
-	patt := regexp.MustCompile(`<dd><a href="(?P<page>/all-dog-breeds/[^.]+.html)">.+?</a></dd>`)
-	pageListRes, _ := utils.NewCacheResponse(listBreeds)
+	cr := newCrawler(c)
 
-	defer pageListRes.Body.Close()
-	P, err := io.ReadAll(pageListRes.Body)
+	dogs, err := cr.run(ctx)
 	if err != nil {
-		log.Fatalf("error reading bytes (err: %v)", err)
+		return nil, err
 	}
 
-	for _, indexes := range patt.FindAllSubmatchIndex(P, -1) {
-		pagePath := string(patt.Expand([]byte{}, []byte(`$page`), P, indexes))
-		queue <- pagePath
-		wg.Add(1)
-		go crawlPage(pagePath)
+	s := c.newSnapshot(dogs, cr.pages())
+	return s, c.saveSnapshotFile(s)
+}
+
+// run crawls every breed page discovered on the sources. If ctx is done before the crawl is
+// finished, the breeds crawled so far are returned along with ctx.Err(). Likewise, if some
+// of the pages could not be crawled the other breeds are returned along with a *CrawlError.
+// The pages disallowed by robots.txt are not fetched, and only reported as blocked.
+func (cr *crawler) run(ctx context.Context) (breeds BreedInfos, err error) {
+	started := time.Now()
+
+	names := make([]BreedSource, 0, len(cr.sources))
+	for _, src := range cr.sources {
+		names = append(names, src.Name())
 	}
 
-	wg.Wait()
-	dogs = fetchResult
+	cr.emit(&CrawlStarted{Sources: names})
+
+	defer func() {
+		r := &CrawlReport{
+			Started:     started,
+			Finished:    time.Now(),
+			Pages:       len(cr.records),
+			Blocked:     sortedKeys(cr.blocked),
+			Unmatched:   cr.unmatched,
+			Unavailable: sortedKeys(cr.unavailable),
+			Errors:      cr.errs,
+		}
+
+		cr.report(r)
+		cr.emit(&CrawlFinished{Report: r, Err: err})
+	}()
+
+	reached := 0
 
-	if P, err := json.Marshal(dogs); err != nil {
-		err := ioutil.WriteFile("/tmp/breeds.json", P, 0660)
-		if err != nil {
-			log.Fatal(err)
+	for _, src := range cr.sources {
+		pages, err := src.Discover(ctx, listFetcher{cr: cr, src: src})
+		if err != nil && !errors.Is(err, ErrBlocked) {
+			cr.fail(ctx, err)
+			continue
+		}
+
+		reached++
+
+		cr.emit(&BreedPagesQueued{Source: src.Name(), Count: len(pages)})
+
+		for _, pageUrl := range pages {
+			if ctx.Err() != nil {
+				break
+			}
+
+			select {
+			case cr.queue <- pageUrl:
+			case <-ctx.Done():
+				continue
+			}
+
+			cr.wg.Add(1)
+			go cr.crawlPage(ctx, src, pageUrl)
 		}
 	}
-	return
-}
 
-func crawlPage(path string) {
-	breedp, _ := utils.NewCacheResponse(string(Source1) + path)
-	defer breedp.Body.Close()
+	cr.wg.Wait()
+	var unmatched []*record
+	cr.fetchResult, unmatched = mergeRecords(cr.records, cr.priority)
 
-	P, err := io.ReadAll(breedp.Body)
-	if err != nil {
-		log.Fatalf("error reading bytes (err: %v)", err)
+	for _, r := range unmatched {
+		cr.unmatched = append(cr.unmatched, r.url)
 	}
 
-	mu.Lock()
-	sum := utils.GetMd5Sum(path)
-	fetchResult[sum] = digPage(P)
+	sort.Strings(cr.unmatched)
 
-	// After digging all information from various resources, we add the default resource into the
-	// references field of the object.
-	//
-	fetchResult[sum].Id = sum
+	if err := ctx.Err(); err != nil {
+		return cr.fetchResult, err
+	}
 
-	getReferencesData(fetchResult[sum], []string{string(Source1) + path})
+	if len(cr.errs) != 0 {
+		return cr.fetchResult, &CrawlError{Errors: cr.errs, Unreachable: reached == 0}
+	}
 
-	mu.Unlock()
-	wg.Done()
-	<-queue
+	return cr.fetchResult, nil
 }
 
-func digPage(P []byte) (bi *BreedInfo) {
-	bi = &BreedInfo{
-		BreedChars: make(map[string]int64),
-		Refs:       make(map[string]any),
+// pages returns the state of the breed pages crawled, as kept in a snapshot.
+func (cr *crawler) pages() map[string]*SnapshotPage {
+	pages := make(map[string]*SnapshotPage, len(cr.records))
+	for _, r := range cr.records {
+		pages[r.url] = &SnapshotPage{
+			Source:    r.source,
+			ETag:      r.etag,
+			Sum:       r.sum,
+			FetchedAt: r.fetchedAt,
+			Breed:     r.breed,
+		}
 	}
 
-	mainFmt := `(?m)(?s)<div class="content">(.|\n)*?`
-	namePatt := regexp.MustCompile(mainFmt + `<h1>(?P<name>[^<]+)<\/h1>`)
-	imagesPatt := regexp.MustCompile(mainFmt + `<div class="slideshow">(?P<images>.+?)<table class="table-03">.*?`)
-	refsPatt := regexp.MustCompile(mainFmt + `(<div class="like">|<h3>References</h3>)(?P<url>.+)<\/ul>`)
-	otherNamesPatt := regexp.MustCompile(mainFmt + `<td>Other names<\/td>.*?<td>(?P<otherNames>[^<]+?)<\/td>`)
-	breedGroupsPatt := regexp.MustCompile(mainFmt + `<td>Breed Group<\/td>.*?<td>(?P<breedGroups>.+?)<\/td>`)
-	originPatt := regexp.MustCompile(mainFmt + `<td>Origin<\/td>.*?<td class\="flag">(?P<origin>.+?)(<\/td>)`)
-	sizePatt := regexp.MustCompile(mainFmt + `<td>Size</td>.*?<td>(?P<size>.+?)<\/td>`)
-	tempPatt := regexp.MustCompile(mainFmt + `<td>Temperament<\/td>.*?<td>*(?P<temperaments>.+?)<\/td>`)
-	colorsPatt := regexp.MustCompile(mainFmt + `<td>Colors<\/td>.*?<td>*(?P<colors>.+?)<\/td>`)
-	typePatt := regexp.MustCompile(mainFmt + `<td>Type<\/td>.*?<td>(?P<type>.+?)<\/td>`)
-	charsPatt := regexp.MustCompile(mainFmt + `<table class="table-02">.*?<tbody>.*Breed Characteristics.*?(?P<chars>.+?)<\/tbody>.*?<\/table>`)
-	lspanPatt := regexp.MustCompile(mainFmt + `<td>Life span<\/td>.*?<td>(?P<start>[\d]+?)-(?P<end>[\d]+?).+?<\/td>`)
-	litterSizePatt := regexp.MustCompile(mainFmt + `<td>Litter Size<\/td>.*?<td>(?P<start>[\d]+?)-(?P<end>[\d]+?).+?<\/td>`)
-	historyPatt := regexp.MustCompile(mainFmt + `<h2>History<\/h2>.*?<td>.*?<p>(?P<history>.+?)<\/p>`)
-
-	indices := namePatt.FindSubmatchIndex(P)
-	bi.Name = string(namePatt.Expand([]byte{}, []byte(`$name`), P, indices))
-
-	indices = typePatt.FindSubmatchIndex(P)
-	bi.Type = string(typePatt.Expand([]byte{}, []byte(`$type`), P, indices))
-
-	indices = refsPatt.FindSubmatchIndex(P)
-	refs := refsPatt.Expand([]byte{}, []byte(`$url`), P, indices)
-	hrefPatt := regexp.MustCompile(`href="(?P<url>.+?)"`)
-	urls := []string{}
-	for _, indices := range hrefPatt.FindAllSubmatchIndex(refs, -1) {
-		href := string(hrefPatt.Expand([]byte{}, []byte(`$url`), refs, indices))
-
-		if strings.Contains(href, "//") {
-			href = regexp.MustCompile(`^//`).ReplaceAllString(href, "https://")
-			urls = append(urls, href)
-			continue
-		}
+	return pages
+}
 
-		urls = append(urls, string(Source1)+href)
-		bi.BreedRecs = append(bi.BreedRecs, utils.GetMd5Sum(href))
+// Fetch fetches a page through the response cache, it is the Fetcher given to the sources.
+// An *Error of kind ErrBlocked is returned for a page disallowed by robots.txt.
+func (cr *crawler) Fetch(ctx context.Context, pageUrl string) (*Page, error) {
+	if err := cr.checkRobots(ctx, pageUrl); err != nil {
+		return nil, err
 	}
 
-	getReferencesData(bi, urls)
-
-	for _, indices := range otherNamesPatt.FindAllSubmatchIndex(P, -1) {
-		otherNames := string(otherNamesPatt.Expand([]byte{}, []byte(`$otherNames`), P, indices))
-		bi.OtherNames = append(bi.OtherNames, strings.Split(otherNames, ",")...)
-		bi.OtherNames = cleanStrings(bi.OtherNames)
+	res, _, err := cr.cache.NewCacheResponse(ctx, pageUrl)
+	if err != nil {
+		return nil, err
 	}
 
-	bi.OtherNames = uniqueSet(bi.OtherNames)
-
-	bi.Origin = getResults(originPatt, "</p>", []byte(`$origin`), P)
-	bi.BreedGroups = getResults(breedGroupsPatt, "</p>", []byte(`$breedGroups`), P)
-	bi.Size = getResults(sizePatt, "to", []byte(`$size`), P)
-	bi.Temperaments = getResults(tempPatt, "</p>", []byte(`$temperaments`), P)
-	bi.Colors = func() []string {
-		results := getResults(colorsPatt, "</p>", []byte(`$colors`), P)
-		maps := make(map[string]int)
-		for _, val := range results {
-			maps[val] = 1
-		}
+	defer res.Body.Close()
 
-		results = make([]string, 0)
+	P, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, &Error{Op: "read cache", URL: pageUrl, Kind: ErrCacheCorrupt, Err: err}
+	}
 
-		for k := range maps {
-			results = append(results, k)
-		}
+	return &Page{URL: pageUrl, Body: P, FetchedAt: res.FetchedAt(), ETag: res.Header.Get("ETag")}, nil
+}
 
-		return results
+func (cr *crawler) crawlPage(ctx context.Context, src Source, pageUrl string) {
+	defer func() {
+		cr.wg.Done()
+		<-cr.queue
 	}()
 
-	indices = charsPatt.FindSubmatchIndex(P)
-	chars := charsPatt.Expand([]byte{}, []byte(`$chars`), P, indices)
-	charsTypePatt := regexp.MustCompile(`(?m)<td>(?P<type>[A-Za-z ]*?)</td>(.|\n)*?<p class="star-0\d">(?P<score>\d) stars<\/p>`)
+	if ctx.Err() != nil {
+		return
+	}
 
-	for _, indices := range charsTypePatt.FindAllSubmatchIndex(chars, -1) {
-		chars := strings.Split(string(charsTypePatt.Expand([]byte{}, []byte(`$type,$score`), chars, indices)), ",")
+	cr.emit(&BreedPageStarted{Source: src.Name(), URL: pageUrl})
 
-		if len(chars[0]) == 0 {
-			continue
+	page, err := src.Fetch(ctx, cr, pageUrl)
+	if err != nil {
+		cr.emit(&BreedPageFailed{Source: src.Name(), URL: pageUrl, Err: err})
+		if !errors.Is(err, ErrBlocked) {
+			cr.fail(ctx, err)
 		}
 
-		score, err := strconv.ParseInt(chars[1], 10, 64)
-		if err != nil {
-			score = 0
-		}
+		return
+	}
 
-		bi.BreedChars[strings.TrimSpace(chars[0])] = score
+	sum := utils.BodySum(page.Body)
+
+	// An unchanged page is neither extracted nor are its references fetched again.
+	if p := cr.prev[pageUrl]; p != nil && p.Source == src.Name() && p.unchanged(page.ETag, sum) {
+		cr.mu.Lock()
+		cr.records = append(cr.records, &record{
+			source:     p.Source,
+			url:        pageUrl,
+			fetchedAt:  p.FetchedAt,
+			breed:      p.Breed,
+			etag:       p.ETag,
+			sum:        p.Sum,
+			reused:     true,
+			attachOnly: attachOnly(src),
+		})
+		cr.mu.Unlock()
+
+		cr.emit(&BreedPageFinished{Source: src.Name(), URL: pageUrl, Reused: true})
+		return
 	}
 
-	indices = imagesPatt.FindSubmatchIndex(P)
-	images := imagesPatt.Expand([]byte{}, []byte(`$images`), P, indices)
-	srcPatt := regexp.MustCompile(`<img.*?src="(?P<imageSrc>\/uploads\/dog-pictures\/[^"]+)"`)
+	bi, err := src.Extract(page)
+	if err != nil {
+		err = &Error{Op: "parse", URL: pageUrl, Kind: ErrParse, Err: err}
+
+		cr.emit(&BreedPageFailed{Source: src.Name(), URL: pageUrl, Err: err})
+		cr.fail(ctx, err)
+		return
+	}
 
-	for _, indices := range srcPatt.FindAllSubmatchIndex(images, -1) {
-		src := string(srcPatt.Expand([]byte{}, []byte(`$imageSrc`), images, indices))
-		bi.Images = append(bi.Images, string(Source1)+src)
+	// The references extracted from the page are fetched along with the page itself, which is
+	// added as the default resource into the references field of the object.
+	if bi.Refs == nil {
+		bi.Refs = make(map[string]any)
 	}
 
-	indices = lspanPatt.FindSubmatchIndex(P)
-	lifespan := lspanPatt.Expand([]byte{}, []byte(`$start-$end`), P, indices)
+	refs := []string{pageUrl}
+	for href, data := range bi.Refs {
+		if data == nil {
+			refs = append(refs, href)
+			delete(bi.Refs, href)
+		}
+	}
 
-	for _, years := range strings.Split(string(lifespan), "-") {
-		y, _ := strconv.ParseUint(years, 10, 64)
-		bi.Lifespan = append(bi.Lifespan, y)
+	path := pageUrl
+	if u, err := URL.Parse(pageUrl); err == nil {
+		path = u.Path
 	}
 
-	indices = litterSizePatt.FindSubmatchIndex(P)
-	litterSize := litterSizePatt.Expand([]byte{}, []byte(`$start-$end`), P, indices)
+	cr.mu.Lock()
+	bi.Id = utils.GetMd5Sum(path)
+	cr.records = append(cr.records, &record{
+		source:     src.Name(),
+		url:        pageUrl,
+		fetchedAt:  page.FetchedAt,
+		breed:      bi,
+		etag:       page.ETag,
+		sum:        sum,
+		attachOnly: attachOnly(src),
+	})
 
-	for _, litter := range strings.Split(string(litterSize), "-") {
-		l, _ := strconv.ParseUint(litter, 10, 64)
-		bi.LitterSize = append(bi.LitterSize, l)
+	cr.getReferencesData(ctx, bi, refs)
+
+	cr.mu.Unlock()
+
+	cr.emit(&BreedPageFinished{Source: src.Name(), URL: pageUrl})
+}
+
+// fail records an error of a breed page. Errors caused by a cancelled crawl are not recorded
+// since the crawl already reports ctx.Err(), and a host which cannot be crawled is only
+// reported once rather than for each of its pages.
+func (cr *crawler) fail(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
 	}
 
-	indices = historyPatt.FindSubmatchIndex(P)
-	history := historyPatt.Expand([]byte{}, []byte(`$history`), P, indices)
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
 
-	bi.History = string(history)
+	var hostErr *HostError
+	if errors.As(err, &hostErr) {
+		if cr.unavailable[hostErr.Host] {
+			return
+		}
 
-	return
+		cr.unavailable[hostErr.Host] = true
+	}
+
+	cr.errs = append(cr.errs, err)
 }
 
-func getReferencesData(bi *BreedInfo, urls []string) {
+// getReferencesData fetches every reference of the breed in the background. References that
+// could not be fetched before ctx is done are left out of bi.Refs.
+func (cr *crawler) getReferencesData(ctx context.Context, bi *BreedInfo, urls []string) {
 	youtubeUrlPatt := regexp.MustCompile("youtube\\.com")
 	pdfPatt := regexp.MustCompile("\\.pdf")
 
 	for _, href := range urls {
-		wg.Add(1)
+		cr.wg.Add(1)
 		go (func(bi *BreedInfo, href string) {
+			defer cr.wg.Done()
+
 			var data any
-			var res *utils.CacheResponse
+
+			select {
+			case cr.refQueue <- struct{}{}:
+				defer func() { <-cr.refQueue }()
+			case <-ctx.Done():
+				return
+			}
 
 			if youtubeUrlPatt.MatchString(href) {
-				mu.Lock()
-				res, _ = utils.NewCacheResponse("https://www.youtube.com/oembed?url=" + href)
-				mu.Unlock()
+				res, err := cr.fetchReference(ctx, "https://www.youtube.com/oembed?url="+href)
+				if err != nil {
+					cr.setUnavailableReference(ctx, bi, href)
+					return
+				}
+
+				defer res.Body.Close()
+
 				P, _ := io.ReadAll(res.Body)
 				data = make(map[string]any)
 
@@ -245,9 +345,14 @@ func getReferencesData(bi *BreedInfo, urls []string) {
 			} else if pdfPatt.MatchString(href) {
 				data = href
 			} else {
-				mu.Lock()
-				res, _ = utils.NewCacheResponse(href)
-				mu.Unlock()
+				res, err := cr.fetchReference(ctx, href)
+				if err != nil {
+					cr.setUnavailableReference(ctx, bi, href)
+					return
+				}
+
+				defer res.Body.Close()
+
 				P, _ := io.ReadAll(res.Body)
 				metaData := make(map[string]any)
 				isSpecialCase := false
@@ -306,24 +411,72 @@ func getReferencesData(bi *BreedInfo, urls []string) {
 				}
 			}
 
-			if res != nil {
-				defer res.Body.Close()
-			}
-
-			mu.Lock()
+			cr.mu.Lock()
 			bi.Refs[href] = data
-			mu.Unlock()
-
-			wg.Done()
+			cr.mu.Unlock()
 		})(bi, href)
 	}
 }
 
-func getResults(patt *regexp.Regexp, sep string, tmp, P []byte) []string {
-	indices := patt.FindSubmatchIndex(P)
-	results := string(patt.Expand([]byte{}, []byte(tmp), P, indices))
-	results = removeMisc(results)
-	return cleanResults(strings.Split(results, sep))
+func (cr *crawler) fetchReference(ctx context.Context, href string) (res *utils.CacheResponse, err error) {
+	defer func() {
+		if ctx.Err() == nil {
+			cr.emit(&ReferenceFetched{URL: href, Err: err})
+		}
+	}()
+
+	if err = cr.checkRobots(ctx, href); err != nil {
+		return nil, err
+	}
+
+	res, _, err = cr.cache.NewCacheResponse(ctx, href)
+	return res, err
+}
+
+// checkRobots returns an *Error of kind ErrBlocked if the robots.txt of the host of pageUrl
+// disallows fetching it, in which case the url is recorded as blocked.
+func (cr *crawler) checkRobots(ctx context.Context, pageUrl string) error {
+	u, err := URL.Parse(pageUrl)
+	if err != nil {
+		return &Error{Op: "fetch", URL: pageUrl, Kind: ErrSourceUnavailable, Err: err}
+	}
+
+	allowed, err := cr.robots.allowed(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		cr.mu.Lock()
+		cr.blocked[pageUrl] = true
+		cr.mu.Unlock()
+
+		return &Error{Op: "fetch", URL: pageUrl, Kind: ErrBlocked}
+	}
+
+	return nil
+}
+
+// setUnavailableReference keeps a reference which could not be fetched by its url, the same
+// way the pdf documents are kept. Nothing is kept if the crawl was cancelled.
+func (cr *crawler) setUnavailableReference(ctx context.Context, bi *BreedInfo, href string) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	cr.mu.Lock()
+	bi.Refs[href] = href
+	cr.mu.Unlock()
+}
+
+func sortedKeys(M map[string]bool) []string {
+	keys := make([]string, 0, len(M))
+	for key := range M {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
 }
 
 func cleanResults(S []string) (s []string) {
@@ -360,16 +513,16 @@ func removeMisc(s string) string {
 	return regexp.MustCompile(`(<img .*?>\s|&nbsp;|<p>|\n|\r|<a .*?>|</a>)`).ReplaceAllString(s, "")
 }
 
+// uniqueSet removes the duplicates of sub, keeping the order in which the names first appear.
 func uniqueSet(sub []string) []string {
 	var M = make(map[string]bool)
 	var res = make([]string, 0)
 
 	for _, name := range sub {
-		M[name] = true
-	}
-
-	for name := range M {
-		res = append(res, name)
+		if !M[name] {
+			M[name] = true
+			res = append(res, name)
+		}
 	}
 
 	return res
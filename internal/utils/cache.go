@@ -1,131 +1,421 @@
 package utils
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
-var (
-	savedCachePath = fmt.Sprintf("%s/.breeds/", os.Getenv("HOME"))
-)
+// DefaultCache is the cache used by the package level NewCacheResponse function.
+var DefaultCache = NewCache(http.DefaultClient)
+
+// CacheDirEnv is the environment variable which overrides the default cache directory.
+const CacheDirEnv = "DOGFETCH_CACHE_DIR"
+
+// Version is the version of dogfetch, which identifies its requests along with ProductToken.
+const Version = "0.2.0"
+
+// ProductToken names dogfetch in the User-Agent of its requests.
+const ProductToken = "dogfetch"
+
+// DefaultUserAgent is the User-Agent of the requests of a cache whose UserAgent is not set.
+const DefaultUserAgent = ProductToken + "/" + Version + " (+https://github.com/rommms07/dogfetch)"
+
+// DefaultTTL is how long a cached response is used before it is revalidated, when the TTL of
+// the cache is not set.
+const DefaultTTL = 4 * time.Minute
+
+// DefaultCachePath returns the directory of the responses cached without a store: the one
+// named by $DOGFETCH_CACHE_DIR, or dogfetch in the cache directory of the user, that is
+// $XDG_CACHE_HOME/dogfetch or ~/.cache/dogfetch on Linux. The directory is only created once
+// a response is written into it.
+func DefaultCachePath() (string, error) {
+	if dir := os.Getenv(CacheDirEnv); len(dir) != 0 {
+		return dir, nil
+	}
 
-func init() {
-	os.Mkdir(savedCachePath, 0750)
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate the cache directory, set $%s: %w", CacheDirEnv, err)
+	}
+
+	return filepath.Join(dir, "dogfetch"), nil
+}
+
+// CacheKey returns the key of the response of resUrl in a CacheStore.
+func CacheKey(resUrl string) string {
+	return getSha512Sum(resUrl)
+}
+
+// Cache keeps the fetched responses in Store, a FileStore of DefaultCachePath when it is nil,
+// and uses Client to fetch the ones it does not have yet. The responses are used for TTL, or
+// DefaultTTL when it is zero, then revalidated with the server. A negative TTL revalidates the
+// responses every time they are used.
+//
+// The requests are sent with the extra headers of Header, and identified by UserAgent, or
+// DefaultUserAgent when it is empty, which replaces any User-Agent found in Header.
+//
+// When Wait is set, it is called before every request made to a server, and the request is
+// given up if it returns an error. It is how the rate of the requests is limited.
+//
+// When Observe is set, it is called with the url of every response returned, telling whether it
+// was read from the store, the ones revalidated with a 304 Not Modified response included.
+//
+// The failed requests are retried as told by Retry. The responses which are still failed
+// after that, including the 4xx ones, are never stored, and an error of kind
+// ErrSourceUnavailable is returned in their place. An expired response is still returned when
+// its revalidation fails for want of a server, rather than being told missing by a 4xx one.
+//
+// The bodies are stored encoded with Codec, CodecGzip when it is empty, and decoded whatever
+// their codec when they are read back.
+//
+// When MaxSize is set, the least recently used responses are evicted from the store once
+// responses taking a tenth of MaxSize were written since the last eviction, so the store may
+// outgrow MaxSize by that much meanwhile.
+type Cache struct {
+	Store     CacheStore
+	Client    *http.Client
+	UserAgent string
+	Header    http.Header
+	TTL       time.Duration
+	Codec     string
+	MaxSize   int64
+	Wait      func(ctx context.Context, u *url.URL) error
+	Retry     RetryPolicy
+	Observe   func(resUrl string, hit bool)
+
+	mu      sync.Mutex
+	written int64
+	pruned  bool
+}
+
+// OpenStore returns the store of the cache.
+func (c *Cache) OpenStore() (CacheStore, error) {
+	if c.Store != nil {
+		return c.Store, nil
+	}
+
+	dir, err := DefaultCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFileStore(dir), nil
+}
+
+func (c *Cache) codec() string {
+	if len(c.Codec) == 0 {
+		return CodecGzip
+	}
+
+	return c.Codec
+}
+
+func (c *Cache) userAgent() string {
+	if len(c.UserAgent) == 0 {
+		return DefaultUserAgent
+	}
+
+	return c.UserAgent
+}
+
+func (c *Cache) ttl() time.Duration {
+	if c.TTL == 0 {
+		return DefaultTTL
+	}
+
+	return c.TTL
+}
+
+// NewCache returns a cache stored in files under the default cache path which fetches its
+// responses through client.
+func NewCache(client *http.Client) *Cache {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Cache{Client: client}
 }
 
 type CacheResponse struct {
 	E_at       time.Time
+	F_at       time.Time
 	Cache_path string
+
+	// Res_url is the url the response was fetched from. It is empty in the entries written
+	// before it was recorded.
+	Res_url string `json:",omitempty"`
+
+	// Body_size and Body_sum, the hex sha256 sum of the body, are recorded by the FileStore to
+	// tell an incomplete body apart. They are empty in the entries written before them.
+	Body_size int64  `json:",omitempty"`
+	Body_sum  string `json:",omitempty"`
+
+	// Body_codec tells how the body is stored, CodecIdentity when it is empty.
+	Body_codec string `json:",omitempty"`
+
 	*http.Response
 }
 
+// FetchedAt returns the time the response was fetched at. The entries cached before F_at was
+// recorded fall back to the Date header sent by the server.
+func (c *CacheResponse) FetchedAt() time.Time {
+	if !c.F_at.IsZero() || c.Response == nil {
+		return c.F_at
+	}
+
+	date, _ := http.ParseTime(c.Header.Get("Date"))
+	return date
+}
+
+// Expired reports whether the response has outlived its TTL and must be revalidated.
+func (c *CacheResponse) Expired() bool {
+	return !time.Now().Before(c.E_at)
+}
+
 /**
  * NewCacheResponse
  *
- * Creates a new cache response by fetching the resUrl parameter value.
+ * Creates a new cache response by fetching the resUrl parameter value. An *Error of kind
+ * ErrSourceUnavailable is returned if the resource cannot be fetched, and one of kind
+ * ErrCacheCorrupt if the cached response cannot be read back.
+ *
+ * An expired response is revalidated with a conditional request built from its ETag and
+ * Last-Modified headers. When the server answers 304 Not Modified, the cached response is
+ * kept for another TTL instead of being downloaded again. When the server cannot be reached,
+ * or still fails with a 5xx or 429 response after the retries, the expired response is
+ * returned as it is, to be revalidated the next time.
  */
-func NewCacheResponse(resUrl string) (cacheRes *CacheResponse, key string) {
+func NewCacheResponse(ctx context.Context, resUrl string) (cacheRes *CacheResponse, key string, err error) {
+	return DefaultCache.NewCacheResponse(ctx, resUrl)
+}
+
+func (c *Cache) NewCacheResponse(ctx context.Context, resUrl string) (cacheRes *CacheResponse, key string, err error) {
 	key = getSha512Sum(resUrl)
 
-	if cacheRes = getCache(resUrl); cacheRes != nil {
+	store, err := c.OpenStore()
+	if err != nil {
+		return nil, key, &Error{Op: "open cache", URL: resUrl, Err: err}
+	}
+
+	cached, err := store.Get(key)
+	if errors.Is(err, ErrCacheMiss) {
+		cached = nil
+	} else if err != nil {
+		return nil, key, &Error{Op: "read cache", URL: resUrl, Kind: ErrCacheCorrupt, Err: err}
+	}
+
+	if cached != nil && !cached.Meta.Expired() {
+		if cacheRes, err = cached.response(resUrl); err == nil {
+			c.observe(resUrl, true)
+		}
+
+		return
+	}
+
+	var validators *CacheResponse
+	if cached != nil {
+		validators = cached.Meta
+	}
+
+	res, err := c.fetchRetry(ctx, resUrl, validators)
+	if err != nil && cached != nil && ctx.Err() == nil && unreachable(err) {
+		if cacheRes, err = cached.response(resUrl); err == nil {
+			c.observe(resUrl, true)
+		}
+
 		return
+	} else if err != nil {
+		return nil, key, err
 	}
 
-	if res := fetch(resUrl); res != nil {
-		cacheRes = mkCacheFrom(resUrl, res)
+	hit := cached != nil && res.StatusCode == http.StatusNotModified
+	if hit {
+		res.Body.Close()
+		extendCache(c, cached.Meta, res)
+	} else if cached, err = c.mkEntry(resUrl, res); err != nil {
+		return nil, key, err
+	}
+
+	if err = store.Put(key, cached); err != nil {
+		return nil, key, &Error{Op: "write cache", URL: resUrl, Err: err}
+	}
+
+	c.evict(store, int64(len(cached.Body)))
+
+	if cacheRes, err = cached.response(resUrl); err == nil {
+		c.observe(resUrl, hit)
 	}
 
 	return
 }
 
-var fetch = func(resUrl string) (cache *http.Response) {
-	// host, _ := url.Parse(resUrl)
-	req, err := http.NewRequest(http.MethodGet, resUrl, nil)
-	if err != nil {
-		log.Fatalf("Something went wrong while creating a new request. (err: %v)", err)
+// unreachable tells whether err, returned by fetchRetry, is caused by a server which could not
+// be reached or failed to answer, rather than by a response telling the resource is missing.
+func unreachable(err error) bool {
+	var status *StatusError
+	if !errors.As(err, &status) {
+		return true
 	}
 
-	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:103.0) Gecko/20100101 Firefox/103.0")
+	return status.StatusCode >= 500 || status.StatusCode == http.StatusTooManyRequests
+}
 
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Fatalf("Something went wrong while fetch the request. (err: %v)", err)
+func (c *Cache) observe(resUrl string, hit bool) {
+	if c.Observe != nil {
+		c.Observe(resUrl, hit)
+	}
+}
+
+// evict prunes the store down to MaxSize, after size bytes were written into it. A failed
+// eviction is tried again after the next write.
+func (c *Cache) evict(store CacheStore, size int64) {
+	if c.MaxSize <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.written += size
+	if c.pruned && c.written < c.MaxSize/10 {
+		return
 	}
 
-	return res
+	if _, err := Prune(store, c.MaxSize); err == nil {
+		c.written, c.pruned = 0, true
+	}
 }
 
-var mkCacheFrom = func(resUrl string, res *http.Response) (cache *CacheResponse) {
-	sum := getSha512Sum(resUrl)
-	cachePath := savedCachePath + sum
+// Decode returns the body of the entry as it was received.
+func (e *Entry) Decode() ([]byte, error) {
+	codec, err := getCodec(e.Meta.Body_codec)
+	if err != nil {
+		return nil, err
+	}
+
+	return codec.decode(e.Body)
+}
 
-	cacheContent, err := os.Create(cachePath + ".cache")
+// response returns the cached response along with a reader of its decoded body.
+func (e *Entry) response(resUrl string) (*CacheResponse, error) {
+	body, err := e.Decode()
 	if err != nil {
-		log.Fatalf("error: cannot create cache (%v)", err)
+		return nil, &Error{Op: "read cache", URL: resUrl, Kind: ErrCacheCorrupt, Err: err}
+	}
+
+	cache := *e.Meta
+	if e.Meta.Response != nil {
+		res := *e.Meta.Response
+		cache.Response = &res
+	} else {
+		cache.Response = &http.Response{}
 	}
 
-	cacheFile, err := os.Create(cachePath + ".json")
+	cache.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return &cache, nil
+}
+
+// fetch requests resUrl, conditionally when the validators of an expired response are given.
+func (c *Cache) fetch(ctx context.Context, resUrl string, cached *CacheResponse) (*http.Response, error) {
+	// host, _ := url.Parse(resUrl)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resUrl, nil)
 	if err != nil {
-		log.Fatalf("error: cannot create file (%v)", err)
+		return nil, &Error{Op: "fetch", URL: resUrl, Kind: ErrSourceUnavailable, Err: err}
 	}
 
-	cache = &CacheResponse{
-		Cache_path: cachePath,
-		E_at:       time.Now().Add(time.Minute * 4),
-		Response:   res,
+	if c.Wait != nil {
+		if err := c.Wait(ctx, req.URL); err != nil {
+			return nil, &Error{Op: "fetch", URL: resUrl, Kind: ErrSourceUnavailable, Err: err}
+		}
 	}
 
-	resBody, err := ioutil.ReadAll(res.Body)
+	for key, vals := range c.Header {
+		req.Header[http.CanonicalHeaderKey(key)] = append([]string{}, vals...)
+	}
+
+	req.Header.Set("User-Agent", c.userAgent())
+
+	if cached != nil && cached.Response != nil {
+		if etag := cached.Header.Get("ETag"); len(etag) != 0 {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		if modified := cached.Header.Get("Last-Modified"); len(modified) != 0 {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	res, err := c.Client.Do(req)
 	if err != nil {
-		log.Fatalf("%v (output: %v)", err, resBody)
+		return nil, &Error{Op: "fetch", URL: resUrl, Kind: ErrSourceUnavailable, Err: err}
 	}
 
+	return res, nil
+}
+
+// mkEntry reads the body of res into a new entry of the cache. The body is read before
+// anything is stored, so a request cancelled midway does not leave a truncated entry behind.
+func (c *Cache) mkEntry(resUrl string, res *http.Response) (*Entry, error) {
+	resBody, err := ioutil.ReadAll(res.Body)
 	res.Body.Close()
 
-	cache.Body = nil
-	cache.Request = nil
-	cache.TLS = nil
+	if err != nil {
+		return nil, &Error{Op: "fetch", URL: resUrl, Kind: ErrSourceUnavailable, Err: err}
+	}
 
-	jsonBody, err := json.Marshal(cache)
+	codec, err := getCodec(c.codec())
 	if err != nil {
-		log.Fatalf("%v (output: %v)", err, jsonBody)
+		return nil, &Error{Op: "write cache", URL: resUrl, Err: err}
+	}
+
+	if resBody, err = codec.encode(resBody); err != nil {
+		return nil, &Error{Op: "write cache", URL: resUrl, Err: err}
 	}
 
-	cacheFile.Write(jsonBody)
-	cacheFile.Close()
+	meta := &CacheResponse{
+		E_at:       time.Now().Add(c.ttl()),
+		F_at:       time.Now(),
+		Res_url:    resUrl,
+		Body_codec: c.codec(),
+		Response:   res,
+	}
 
-	cacheContent.Write(resBody)
-	cacheContent.Close()
+	meta.Body = nil
+	meta.Request = nil
+	meta.TLS = nil
 
-	return getCache(resUrl)
+	return &Entry{Meta: meta, Body: resBody}, nil
 }
 
-var getCache = func(resUrl string) (cache *CacheResponse) {
-	key := getSha512Sum(resUrl)
-	cachePath := savedCachePath + key
+// revalidatedHeaders are the headers of a 304 Not Modified response which replace the ones of
+// the cached response.
+var revalidatedHeaders = []string{"Cache-Control", "Date", "ETag", "Expires", "Last-Modified"}
 
-	P, err := ioutil.ReadFile(cachePath + ".json")
-	if err != nil {
-		return nil
+// extendCache keeps the cached response for another TTL after the server answered res, a 304
+// Not Modified response, to its revalidation.
+func extendCache(c *Cache, cache *CacheResponse, res *http.Response) {
+	if cache.Response == nil {
+		cache.Response = &http.Response{}
 	}
 
-	cache = &CacheResponse{}
-
-	err = json.Unmarshal(P, cache)
-	if err != nil {
-		log.Fatalf("cannot unmarshal cache. (error: %v)", err)
+	if cache.Header == nil {
+		cache.Header = make(http.Header)
 	}
 
-	cacheContent, err := os.Open(cachePath + ".cache")
-	if err != nil {
-		log.Fatalf("cannot read content of the cache. (error: %v)", err)
+	for _, key := range revalidatedHeaders {
+		if vals := res.Header.Values(key); len(vals) != 0 {
+			cache.Header[key] = vals
+		}
 	}
 
-	cache.Body = cacheContent
-	return
+	cache.E_at = time.Now().Add(c.ttl())
 }
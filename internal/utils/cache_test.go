@@ -1,47 +1,66 @@
 package utils
 
 import (
-	"log"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 )
 
-var testInMemCaches = make(map[string]*CacheResponse)
+// fakeTransport answers every request without going to the network, recording the urls it
+// was asked for.
+type fakeTransport struct {
+	mu       sync.Mutex
+	requests []string
+}
 
-var fakeFetch = func(resUrl string) (cache *http.Response) {
-	log.Println("called fakeFetch!")
+func (ft *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ft.mu.Lock()
+	ft.requests = append(ft.requests, req.URL.String())
+	ft.mu.Unlock()
 
 	testHdr := http.Header{}
 
 	testHdr.Add("X-TESTING-MODE", "blahblah")
-	testHdr.Add("X-TESTING-URL", resUrl)
+	testHdr.Add("X-TESTING-URL", req.URL.String())
 
 	return &http.Response{
-		Status:     "OK",
+		Status:     "200 OK",
 		StatusCode: 200,
 		Header:     testHdr,
-	}
+		Body:       ioutil.NopCloser(strings.NewReader("body of " + req.URL.String())),
+		Request:    req,
+	}, nil
 }
 
-var fakeMkCacheFrom = func(resUrl string, res *http.Response) *CacheResponse {
-	log.Println("called fakeMkCacheFrom")
-
-	sum := getSha512Sum(resUrl)
+func gunzip(t *testing.T, P []byte) []byte {
+	r, err := gzip.NewReader(bytes.NewReader(P))
+	if err != nil {
+		t.Fatalf("(fail) Expected a gzip-compressed body. (err: %v)", err)
+	}
 
-	testInMemCaches[sum] = &CacheResponse{
-		E_at:       time.Now(),
-		Cache_path: sum,
-		Response:   res,
+	P, err = ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("(fail) Unable to decompress the body. (err: %v)", err)
 	}
 
-	return testInMemCaches[sum]
+	return P
 }
 
-var fakeGetCache = func(resUrl string) *CacheResponse {
-	log.Println("called fakeGetCache")
-	return testInMemCaches[getSha512Sum(resUrl)]
+func newFakeCache() (*Cache, *fakeTransport) {
+	ft := &fakeTransport{}
+	return &Cache{Store: NewMemoryStore(), Client: &http.Client{Transport: ft}}, ft
 }
 
 func Test_NewCacheResponse(t *testing.T) {
@@ -56,48 +75,58 @@ func Test_NewCacheResponse(t *testing.T) {
 		},
 	}
 
+	c, ft := newFakeCache()
+
 	for _, T := range tests {
-		unloadFakeFuncs := loadFakeFuncs()
-		_, key := NewCacheResponse(T.input)
+		res, key, err := c.NewCacheResponse(context.Background(), T.input)
+		if err != nil {
+			t.Errorf("(fail) input: %s (err: %v)", T.input, err)
+			continue
+		}
 
 		if T.expected != key {
 			t.Errorf("(fail) input: %s (expected: %s)", T.input, T.expected)
 			continue
 		}
 
-		var fakeRes *CacheResponse
+		P, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
 
-		// Check if the map `testInMemCaches` declared above is populated as expected.
-		if testRes, exists := testInMemCaches[key]; !exists {
-			t.Errorf("(fail) input: %s (did not populate testInMemCaches properly)", T.input)
+		if res.StatusCode != 200 ||
+			res.Header.Get("X-TESTING-MODE") != "blahblah" ||
+			res.Header.Get("X-TESTING-URL") != T.input ||
+			string(P) != "body of "+T.input {
+
+			t.Errorf("(fail) input: %s (did not contain the expected testing headers and response fields)", T.input)
 			continue
-		} else {
-			fakeRes = testRes
 		}
 
-		if fakeRes.Status != "OK" &&
-			fakeRes.StatusCode != 200 &&
-			fakeRes.Header.Get("X-TESTING-MODE") != "blahblah" &&
-			fakeRes.Header.Get("X-TESTING-URL") != T.input &&
-			fakeRes.Cache_path != T.expected {
-
-			t.Errorf("(fail) input: %s (did not contain the expected testing headers and response fields)", T.input)
+		// Check if the store is populated as expected.
+		if e, err := c.Store.Get(key); err != nil || e.Meta.Body_codec != CodecGzip || string(gunzip(t, e.Body)) != string(P) {
+			t.Errorf("(fail) input: %s (did not populate the store properly) (err: %v)", T.input, err)
 			continue
 		}
 
-		unloadFakeFuncs()
+		if info, err := c.Store.Stat(key); err != nil || info.URL != T.input || info.Status != 200 || info.Codec != CodecGzip {
+			t.Errorf("(fail) input: %s (did not describe the entry) (output: %+v, err: %v)", T.input, info, err)
+		}
 
-		// Using the real implementation of fetch, we test NewCacheResponse.
-		res, rkey := NewCacheResponse(T.input)
-		defer res.Body.Close()
+		// The second time, the response is read back from the store.
+		res, rkey, err := c.NewCacheResponse(context.Background(), T.input)
+		if err != nil {
+			t.Errorf("(fail) input: %s (err: %v)", T.input, err)
+			continue
+		}
+
+		res.Body.Close()
 
 		if rkey != T.expected {
 			t.Errorf("(fail) input: %s (did not match the output fake key.", T.input)
 		}
+	}
 
-		if res.Cache_path != savedCachePath+T.expected {
-			t.Errorf("(fail) input: %s (did not match the expected path).\n\t(output: %v)", rkey, res.Cache_path)
-		}
+	if len(ft.requests) != len(tests) {
+		t.Errorf("(fail) Expected the cached responses not to be fetched again. (output: %v)", ft.requests)
 	}
 }
 
@@ -123,33 +152,477 @@ func Test_NewCacheResponseConcurrently(t *testing.T) {
 
 	var wg sync.WaitGroup
 
+	c, _ := newFakeCache()
+
 	for _, T := range tests {
 		wg.Add(1)
 		go func(input string) {
-			res, _ := NewCacheResponse(input)
+			defer wg.Done()
+
+			res, _, err := c.NewCacheResponse(context.Background(), input)
+			if err != nil {
+				t.Errorf("(fail) input: %s (err: %v)", input, err)
+				return
+			}
+
 			res.Body.Close()
-			wg.Done()
 		}(T.input)
 	}
 
 	wg.Wait()
 }
 
-func loadFakeFuncs() (unloadFakeFuncs func()) {
-	var savedFetch = fetch
-	var savedGetCache = getCache
-	var savedMkCacheFrom = mkCacheFrom
+func Test_NewCacheResponseCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c, _ := newFakeCache()
+	c.Client = http.DefaultClient
+
+	res, _, err := c.NewCacheResponse(ctx, "https://www.dogbreedslist.info/cancelled/")
+	if res != nil {
+		t.Errorf("(fail) Expected no response from a cancelled context. (output: %v)", res)
+	}
+
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, ErrSourceUnavailable) {
+		t.Errorf("(fail) Expected a cancelled ErrSourceUnavailable error. (output: %v)", err)
+	}
+}
+
+func Test_NewCacheResponseHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
 
-	// Substitute fake functions
-	fetch = fakeFetch
-	getCache = fakeGetCache
-	mkCacheFrom = fakeMkCacheFrom
+	tests := []struct {
+		cache     *Cache
+		userAgent string
+	}{
+		{&Cache{}, DefaultUserAgent},
+		{&Cache{UserAgent: "crawler/1.0", Header: http.Header{"user-agent": {"ignored"}, "X-Team": {"dogs"}}}, "crawler/1.0"},
+	}
+
+	for i, T := range tests {
+		T.cache.Store = NewMemoryStore()
+		T.cache.Client = http.DefaultClient
+
+		res, _, err := T.cache.NewCacheResponse(context.Background(), fmt.Sprintf("%s/%d", srv.URL, i))
+		if err != nil {
+			t.Fatalf("(fail) Unable to fetch the response. (err: %v)", err)
+		}
 
-	unloadFakeFuncs = func() {
-		fetch = savedFetch
-		getCache = savedGetCache
-		mkCacheFrom = savedMkCacheFrom
+		res.Body.Close()
+
+		if ua := got.Values("User-Agent"); len(ua) != 1 || ua[0] != T.userAgent {
+			t.Errorf("(fail) Expected the User-Agent %q. (output: %q)", T.userAgent, ua)
+		}
+
+		if want := T.cache.Header.Get("X-Team"); got.Get("X-Team") != want {
+			t.Errorf("(fail) Expected the header X-Team %q. (output: %q)", want, got.Get("X-Team"))
+		}
 	}
+}
+
+func Test_FileStoreIncomplete(t *testing.T) {
+	c, ft := newFakeCache()
+	store := NewFileStore(t.TempDir())
+	c.Store = store
+
+	resUrl := "https://www.dogbreedslist.info/incomplete/"
+	key := getSha512Sum(resUrl)
+	cachePath := filepath.Join(store.Dir, key)
+
+	get := func() {
+		res, _, err := c.NewCacheResponse(context.Background(), resUrl)
+		if err != nil {
+			t.Fatalf("(fail) input: %s (err: %v)", resUrl, err)
+		}
+
+		P, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if string(P) != "body of "+resUrl {
+			t.Errorf("(fail) Did not read the expected body. (output: %s)", P)
+		}
+	}
+
+	get()
+
+	tests := map[string]func(){
+		"truncated body": func() { os.Truncate(cachePath+".cache", 4) },
+		"changed body":   func() { ioutil.WriteFile(cachePath+".cache", []byte("body of something else"), 0640) },
+		"missing body":   func() { os.Remove(cachePath + ".cache") },
+		"corrupt meta":   func() { ioutil.WriteFile(cachePath+".json", []byte(`{"E_at": "2022-`), 0640) },
+	}
+
+	for name, corrupt := range tests {
+		corrupt()
+
+		if e, err := store.Get(key); e != nil || !errors.Is(err, ErrCacheMiss) {
+			t.Errorf("(fail) Expected the entry to be discarded. (input: %s, output: %v, err: %v)", name, e, err)
+		}
+
+		if _, err := os.Stat(cachePath + ".json"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("(fail) Did not remove the metadata of the discarded entry. (input: %s)", name)
+		}
+
+		// The discarded entry is fetched again.
+		requests := len(ft.requests)
+		get()
+
+		if len(ft.requests) != requests+1 {
+			t.Errorf("(fail) Expected the discarded entry to be fetched again. (input: %s)", name)
+		}
+	}
+
+	// The entries written before the size and checksum were recorded are still read.
+	e, _ := store.Get(key)
+	e.Meta.Body_sum = ""
+
+	P, _ := marshalMeta(e.Meta)
+	ioutil.WriteFile(cachePath+".json", P, 0640)
+
+	if e, err := store.Get(key); err != nil || string(gunzip(t, e.Body)) != "body of "+resUrl {
+		t.Errorf("(fail) Did not read the entry without a checksum. (output: %v, err: %v)", e, err)
+	}
+
+	// A body without metadata, and a temporary file left behind long ago, are removed.
+	orphan := filepath.Join(store.Dir, "orphan")
+	ioutil.WriteFile(orphan+".cache", []byte("orphaned body"), 0640)
+	ioutil.WriteFile(orphan+".cache.123.tmp", []byte("orphaned"), 0640)
+	os.Chtimes(orphan+".cache.123.tmp", time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour))
+
+	if keys, err := store.List(); err != nil || len(keys) != 1 || keys[0] != key {
+		t.Errorf("(fail) Did not list the expected entries. (output: %v, err: %v)", keys, err)
+	}
+
+	for _, path := range []string{orphan + ".cache", orphan + ".cache.123.tmp"} {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("(fail) Did not remove the orphaned file: %s", path)
+		}
+	}
+}
+
+func Test_FileStoreConcurrently(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+
+		go func(i int) {
+			defer wg.Done()
+
+			body := strings.Repeat(fmt.Sprint(i), 64<<10)
+			if err := store.Put("key", &Entry{Meta: &CacheResponse{E_at: time.Now()}, Body: []byte(body)}); err != nil {
+				t.Errorf("(fail) Unable to put the entry. (err: %v)", err)
+			}
+		}(i)
+
+		go func() {
+			defer wg.Done()
+
+			e, err := store.Get("key")
+			if errors.Is(err, ErrCacheMiss) {
+				return
+			}
+
+			if err != nil || int64(len(e.Body)) != e.Meta.Body_size || BodySum(e.Body) != e.Meta.Body_sum {
+				t.Errorf("(fail) Read an incomplete entry. (err: %v)", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func Test_NewCacheResponseRevalidate(t *testing.T) {
+	var requests, revalidations int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if r.Header.Get("If-None-Match") == `"v1"` && r.Header.Get("If-Modified-Since") == "Sun, 14 Aug 2022 10:00:00 GMT" {
+			revalidations++
+			w.Header().Set("ETag", `"v1"`)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Sun, 14 Aug 2022 10:00:00 GMT")
+		w.Write([]byte("cached body"))
+	}))
+	defer srv.Close()
+
+	c := &Cache{Store: NewMemoryStore(), Client: srv.Client(), TTL: time.Hour}
+
+	get := func() *CacheResponse {
+		res, _, err := c.NewCacheResponse(context.Background(), srv.URL)
+		if err != nil {
+			t.Fatalf("(fail) input: %s (err: %v)", srv.URL, err)
+		}
+
+		defer res.Body.Close()
+
+		P, _ := ioutil.ReadAll(res.Body)
+		if string(P) != "cached body" {
+			t.Errorf("(fail) Did not read the cached body. (output: %s)", P)
+		}
+
+		return res
+	}
+
+	first := get()
+	get()
+
+	if requests != 1 {
+		t.Errorf("(fail) Expected the fresh response to be read from the cache. (requests: %d)", requests)
+	}
+
+	// Expire the entry, as if its TTL had gone by.
+	key := getSha512Sum(srv.URL)
 
-	return
+	e, err := c.Store.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.Meta.E_at = time.Now().Add(-time.Second)
+	if err := c.Store.Put(key, e); err != nil {
+		t.Fatal(err)
+	}
+
+	res := get()
+
+	if requests != 2 || revalidations != 1 {
+		t.Errorf("(fail) Expected the expired response to be revalidated. (requests: %d, revalidations: %d)", requests, revalidations)
+	}
+
+	if res.Expired() || !res.E_at.After(time.Now().Add(time.Hour-time.Minute)) {
+		t.Errorf("(fail) Did not extend the lifetime of the revalidated response. (output: %v)", res.E_at)
+	}
+
+	if !res.F_at.Equal(first.F_at) {
+		t.Errorf("(fail) Did not expect the revalidated response to be downloaded again. (%v != %v)", res.F_at, first.F_at)
+	}
+
+	get()
+
+	if requests != 2 {
+		t.Errorf("(fail) Expected the extended response to be read from the cache. (requests: %d)", requests)
+	}
+}
+
+func Test_NewCacheResponseExpired(t *testing.T) {
+	var requests int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if len(r.Header.Get("If-None-Match")) != 0 || len(r.Header.Get("If-Modified-Since")) != 0 {
+			t.Errorf("(fail) Did not expect a conditional request without validators.")
+		}
+
+		fmt.Fprintf(w, "body %d", requests)
+	}))
+	defer srv.Close()
+
+	c := &Cache{Store: NewFileStore(t.TempDir()), Client: srv.Client(), TTL: -1}
+
+	for i := 1; i <= 2; i++ {
+		res, _, err := c.NewCacheResponse(context.Background(), srv.URL)
+		if err != nil {
+			t.Fatalf("(fail) input: %s (err: %v)", srv.URL, err)
+		}
+
+		P, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if string(P) != fmt.Sprintf("body %d", i) {
+			t.Errorf("(fail) Expected the expired response to be downloaded again. (output: %s)", P)
+		}
+	}
+
+	// An expired response which cannot be revalidated is used as it is, unless the server
+	// tells it is gone.
+	tests := []struct {
+		transport http.RoundTripper
+		stale     bool
+	}{
+		{&scriptedTransport{statuses: []int{0}}, true},
+		{&scriptedTransport{statuses: []int{http.StatusServiceUnavailable}}, true},
+		{&scriptedTransport{statuses: []int{http.StatusTooManyRequests}}, true},
+		{&scriptedTransport{statuses: []int{http.StatusNotFound}}, false},
+	}
+
+	for _, T := range tests {
+		c.Client = &http.Client{Transport: T.transport}
+		c.Retry = RetryPolicy{Attempts: 1}
+
+		res, _, err := c.NewCacheResponse(context.Background(), srv.URL)
+		if !T.stale {
+			if res != nil || !errors.Is(err, ErrSourceUnavailable) {
+				t.Errorf("(fail) Expected ErrSourceUnavailable for a missing resource. (output: %v)", err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("(fail) Expected the expired response to be used. (err: %v)", err)
+		}
+
+		P, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if string(P) != "body 2" {
+			t.Errorf("(fail) Expected the expired response. (output: %s)", P)
+		}
+	}
+
+	// Nothing is used once the request is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c.Client = srv.Client()
+
+	if res, _, err := c.NewCacheResponse(ctx, srv.URL); res != nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("(fail) Expected a cancelled request to fail. (output: %v)", err)
+	}
+}
+
+func Test_NewCacheResponseCodecs(t *testing.T) {
+	tests := []struct {
+		codec    string
+		expected string
+	}{
+		{codec: "", expected: CodecGzip},
+		{codec: CodecGzip, expected: CodecGzip},
+		{codec: CodecIdentity, expected: CodecIdentity},
+	}
+
+	for _, T := range tests {
+		c, _ := newFakeCache()
+		c.Codec = T.codec
+
+		resUrl := "https://www.dogbreedslist.info/" + strings.Repeat("long/", 100)
+		key := getSha512Sum(resUrl)
+
+		res, _, err := c.NewCacheResponse(context.Background(), resUrl)
+		if err != nil {
+			t.Fatalf("(fail) input: %s (err: %v)", T.codec, err)
+		}
+
+		P, _ := ioutil.ReadAll(res.Body)
+		if string(P) != "body of "+resUrl {
+			t.Errorf("(fail) Did not decode the body. (input: %s, output: %s)", T.codec, P)
+		}
+
+		e, _ := c.Store.Get(key)
+		if e.Meta.Body_codec != T.expected {
+			t.Errorf("(fail) Did not record the codec. (input: %s, output: %s)", T.codec, e.Meta.Body_codec)
+		}
+
+		if T.expected == CodecGzip && len(e.Body) >= len(P) {
+			t.Errorf("(fail) Expected the stored body to be compressed. (%d >= %d)", len(e.Body), len(P))
+		}
+	}
+
+	// The entries stored before the codec was recorded are read as they are.
+	c, ft := newFakeCache()
+	resUrl := "https://www.dogbreedslist.info/legacy/"
+
+	c.Store.Put(getSha512Sum(resUrl), &Entry{
+		Meta: &CacheResponse{E_at: time.Now().Add(time.Hour), Response: &http.Response{StatusCode: 200}},
+		Body: []byte("legacy body"),
+	})
+
+	res, _, err := c.NewCacheResponse(context.Background(), resUrl)
+	if err != nil {
+		t.Fatalf("(fail) input: %s (err: %v)", resUrl, err)
+	}
+
+	if P, _ := ioutil.ReadAll(res.Body); string(P) != "legacy body" || len(ft.requests) != 0 {
+		t.Errorf("(fail) Did not read the uncompressed entry. (output: %s)", P)
+	}
+
+	// A body which cannot be decoded is reported as corrupt.
+	c.Store.Put(getSha512Sum(resUrl), &Entry{
+		Meta: &CacheResponse{E_at: time.Now().Add(time.Hour), Body_codec: CodecGzip},
+		Body: []byte("not gzip"),
+	})
+
+	if _, _, err := c.NewCacheResponse(context.Background(), resUrl); !errors.Is(err, ErrCacheCorrupt) {
+		t.Errorf("(fail) Expected an ErrCacheCorrupt error. (output: %v)", err)
+	}
+}
+
+func Test_DefaultCachePath(t *testing.T) {
+	t.Setenv(CacheDirEnv, "/tmp/dogfetch-env")
+
+	if dir, err := DefaultCachePath(); err != nil || dir != "/tmp/dogfetch-env" {
+		t.Errorf("(fail) Did not take the cache directory from $%s. (output: %s, err: %v)", CacheDirEnv, dir, err)
+	}
+
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	t.Setenv(CacheDirEnv, "")
+	t.Setenv("XDG_CACHE_HOME", "/tmp/xdg")
+
+	if dir, err := DefaultCachePath(); err != nil || dir != "/tmp/xdg/dogfetch" {
+		t.Errorf("(fail) Did not follow $XDG_CACHE_HOME. (output: %s, err: %v)", dir, err)
+	}
+
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("HOME", "")
+
+	if dir, err := DefaultCachePath(); err == nil {
+		t.Errorf("(fail) Expected an error without a cache directory. (output: %s)", dir)
+	}
+
+	// The cache reports it, instead of writing anywhere.
+	c, ft := newFakeCache()
+	c.Store = nil
+
+	if _, _, err := c.NewCacheResponse(context.Background(), "https://www.dogbreedslist.info/"); err == nil || len(ft.requests) != 0 {
+		t.Errorf("(fail) Expected an error without a cache directory. (output: %v)", err)
+	}
+}
+
+func Test_FileStoreLazyDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "not", "yet")
+	store := NewFileStore(dir)
+
+	if _, err := store.Get("a"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("(fail) Expected a cache miss. (err: %v)", err)
+	}
+
+	if keys, err := store.List(); err != nil || len(keys) != 0 {
+		t.Errorf("(fail) Expected no entries. (output: %v, err: %v)", keys, err)
+	}
+
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("(fail) Did not expect the directory to be created by a read.")
+	}
+
+	if err := store.Put("a", testEntry("body")); err != nil {
+		t.Fatalf("(fail) Unable to put the entry. (err: %v)", err)
+	}
+
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		t.Errorf("(fail) Expected the directory to be created by a write. (err: %v)", err)
+	}
+
+	// The directory cannot be created under a file.
+	ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0640)
+
+	if err := NewFileStore(filepath.Join(dir, "file", "sub")).Put("a", testEntry("body")); err == nil {
+		t.Errorf("(fail) Expected an error creating the directory.")
+	}
 }
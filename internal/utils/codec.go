@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+)
+
+const (
+	// CodecGzip stores the bodies of the responses gzip-compressed. It is the default codec.
+	CodecGzip = "gzip"
+
+	// CodecIdentity stores the bodies of the responses as they were received.
+	CodecIdentity = "identity"
+)
+
+type codec struct {
+	encode func(P []byte) ([]byte, error)
+	decode func(P []byte) ([]byte, error)
+}
+
+var codecs = map[string]*codec{
+	CodecIdentity: {
+		encode: func(P []byte) ([]byte, error) { return P, nil },
+		decode: func(P []byte) ([]byte, error) { return P, nil },
+	},
+
+	CodecGzip: {
+		encode: func(P []byte) ([]byte, error) {
+			var buf bytes.Buffer
+
+			w := gzip.NewWriter(&buf)
+			if _, err := w.Write(P); err != nil {
+				return nil, err
+			}
+
+			if err := w.Close(); err != nil {
+				return nil, err
+			}
+
+			return buf.Bytes(), nil
+		},
+
+		decode: func(P []byte) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(P))
+			if err != nil {
+				return nil, err
+			}
+
+			defer r.Close()
+			return ioutil.ReadAll(r)
+		},
+	},
+}
+
+// getCodec returns the codec named name. The entries stored before the codec was recorded have
+// none, and were stored as received.
+func getCodec(name string) (*codec, error) {
+	if len(name) == 0 {
+		name = CodecIdentity
+	}
+
+	if c := codecs[name]; c != nil {
+		return c, nil
+	}
+
+	return nil, fmt.Errorf("unknown codec %q", name)
+}
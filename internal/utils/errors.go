@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrSourceUnavailable is reported when a resource cannot be fetched from its source.
+	ErrSourceUnavailable = errors.New("source unavailable")
+
+	// ErrCacheCorrupt is reported when a cached response cannot be read back.
+	ErrCacheCorrupt = errors.New("cache corrupt")
+
+	// ErrCacheMiss is reported by a CacheStore which has no entry for a key.
+	ErrCacheMiss = errors.New("not cached")
+
+	// ErrIncomplete is reported by a Peeker for an entry which was not completely written.
+	ErrIncomplete = errors.New("incomplete entry")
+)
+
+// Error describes an operation on a resource which failed. Kind is one of the sentinel
+// errors above, so errors.Is(err, ErrCacheCorrupt) reports whether a cache read failed,
+// while Err holds the underlying cause.
+type Error struct {
+	Op   string
+	URL  string
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	msg := e.Op
+	if len(e.URL) != 0 {
+		msg += " " + e.URL
+	}
+
+	if e.Kind != nil {
+		msg += ": " + e.Kind.Error()
+	}
+
+	if e.Err != nil {
+		msg += fmt.Sprintf(" (err: %v)", e.Err)
+	}
+
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// StatusError is the cause of an Error of a request answered with an unexpected status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return "unexpected status " + e.Status
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter limits the rate of the requests made to every host with a token bucket per host.
+// Each bucket holds up to Burst requests, and is refilled with Rate requests per second. A
+// Limiter with a Rate of zero or less, or a nil one, does not limit anything.
+type Limiter struct {
+	Rate  float64
+	Burst int
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{Rate: rate, Burst: burst}
+}
+
+// Wait blocks until a request can be made to host, or until ctx is done in which case
+// ctx.Err() is returned.
+func (l *Limiter) Wait(ctx context.Context, host string) error {
+	if l == nil || l.Rate <= 0 {
+		return nil
+	}
+
+	delay := l.reserve(host)
+	if delay <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.release(host)
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token from the bucket of host, and returns how long to wait until it is
+// actually available.
+func (l *Limiter) reserve(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	burst := float64(l.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	if l.buckets == nil {
+		l.buckets = make(map[string]*bucket)
+	}
+
+	now := time.Now()
+
+	b := l.buckets[host]
+	if b == nil {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[host] = b
+	}
+
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * l.Rate
+		b.last = now
+	}
+
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / l.Rate * float64(time.Second))
+}
+
+// release gives back the token reserved by a request which was given up.
+func (l *Limiter) release(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b := l.buckets[host]; b != nil {
+		b.tokens++
+	}
+}
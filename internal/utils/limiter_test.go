@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_Limiter(t *testing.T) {
+	l := NewLimiter(50, 2)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(context.Background(), "a.example"); err != nil {
+			t.Fatalf("(fail) Unable to wait for the limiter. (err: %v)", err)
+		}
+	}
+
+	// The burst goes through at once, then a request every 20ms.
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond || elapsed > time.Second {
+		t.Errorf("(fail) Expected the requests past the burst to wait. (elapsed: %v)", elapsed)
+	}
+
+	// Every host has a bucket of its own.
+	start = time.Now()
+	for i := 0; i < 2; i++ {
+		l.Wait(context.Background(), "b.example")
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("(fail) Did not expect the requests to another host to wait. (elapsed: %v)", elapsed)
+	}
+}
+
+func Test_LimiterCancelled(t *testing.T) {
+	l := NewLimiter(1, 1)
+	l.Wait(context.Background(), "a.example")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx, "a.example"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("(fail) Expected the wait to stop with the context. (output: %v)", err)
+	}
+
+	// The token of the request given up is given back.
+	if b := l.buckets["a.example"]; b.tokens < -0.5 {
+		t.Errorf("(fail) Did not expect the request given up to hold a token. (output: %v)", b.tokens)
+	}
+}
+
+func Test_LimiterUnlimited(t *testing.T) {
+	for _, l := range []*Limiter{nil, NewLimiter(0, 0)} {
+		start := time.Now()
+		for i := 0; i < 100; i++ {
+			if err := l.Wait(context.Background(), "a.example"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+			t.Errorf("(fail) Did not expect an unlimited limiter to wait. (elapsed: %v)", elapsed)
+		}
+	}
+}
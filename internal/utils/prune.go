@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"errors"
+	"sort"
+)
+
+// PruneStats tells how many entries a prune removed, and the size they took.
+type PruneStats struct {
+	Entries int
+	Bytes   int64
+}
+
+// Prune removes the least recently accessed entries of store until the remaining ones take at
+// most maxSize bytes. The stores which can shrink once entries are deleted, such as the
+// ArchiveStore, are compacted afterwards.
+func Prune(store CacheStore, maxSize int64) (stats PruneStats, err error) {
+	keys, err := store.List()
+	if err != nil {
+		return
+	}
+
+	var infos []*EntryInfo
+	var size int64
+
+	for _, key := range keys {
+		info, err := store.Stat(key)
+		if errors.Is(err, ErrCacheMiss) {
+			continue
+		} else if err != nil {
+			return stats, err
+		}
+
+		infos = append(infos, info)
+		size += info.Size
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].AccessedAt.Equal(infos[j].AccessedAt) {
+			return infos[i].AccessedAt.Before(infos[j].AccessedAt)
+		}
+
+		return infos[i].Key < infos[j].Key
+	})
+
+	for _, info := range infos {
+		if size <= maxSize {
+			break
+		}
+
+		if err = store.Delete(info.Key); err != nil {
+			return
+		}
+
+		size -= info.Size
+		stats.Entries++
+		stats.Bytes += info.Size
+	}
+
+	if c, ok := store.(interface{ Compact() error }); ok && stats.Entries != 0 {
+		err = c.Compact()
+	}
+
+	return
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPrune(t *testing.T, store CacheStore) {
+	for _, key := range []string{"a", "b", "c"} {
+		if err := store.Put(key, testEntry(strings.Repeat(key, 100))); err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Reading a makes b the least recently used entry.
+	if _, err := store.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := Prune(store, 250)
+	if err != nil {
+		t.Fatalf("(fail) Unable to prune the store. (err: %v)", err)
+	}
+
+	if stats.Entries != 1 || stats.Bytes != 100 {
+		t.Errorf("(fail) Did not report the pruned entries. (output: %+v)", stats)
+	}
+
+	keys, _ := store.List()
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "c" {
+		t.Errorf("(fail) Did not prune the least recently used entry. (output: %v)", keys)
+	}
+
+	if stats, _ := Prune(store, 200); stats.Entries != 0 {
+		t.Errorf("(fail) Did not expect a store within its size to be pruned. (output: %+v)", stats)
+	}
+
+	if stats, _ := Prune(store, 0); stats.Entries != 2 {
+		t.Errorf("(fail) Expected every entry to be pruned. (output: %+v)", stats)
+	}
+}
+
+func Test_Prune(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		testPrune(t, NewFileStore(t.TempDir()))
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		testPrune(t, NewMemoryStore())
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.archive")
+
+		store, err := OpenArchiveStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer store.Close()
+
+		testPrune(t, store)
+
+		// The pruned archive is compacted.
+		if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+			t.Errorf("(fail) Expected the archive to be compacted. (output: %v, err: %v)", fi, err)
+		}
+
+		if err := store.Put("d", testEntry("body d")); err != nil {
+			t.Fatal(err)
+		}
+
+		if e, err := store.Get("d"); err != nil || string(e.Body) != "body d" {
+			t.Errorf("(fail) Did not get the entry back from the compacted archive. (err: %v)", err)
+		}
+	})
+}
+
+func Test_NewCacheResponseMaxSize(t *testing.T) {
+	c, _ := newFakeCache()
+	c.Codec = CodecIdentity
+	c.MaxSize = 1000
+
+	for i := 0; i < 50; i++ {
+		resUrl := fmt.Sprintf("https://www.dogbreedslist.info/%03d/", i)
+
+		res, _, err := c.NewCacheResponse(context.Background(), resUrl)
+		if err != nil {
+			t.Fatalf("(fail) input: %s (err: %v)", resUrl, err)
+		}
+
+		res.Body.Close()
+	}
+
+	keys, _ := c.Store.List()
+
+	var size int64
+	for _, key := range keys {
+		info, _ := c.Store.Stat(key)
+		size += info.Size
+	}
+
+	if size > c.MaxSize+c.MaxSize/10 {
+		t.Errorf("(fail) Expected the cache to be kept within its size. (output: %d)", size)
+	}
+
+	// The last response is among the most recently used ones, so it was kept.
+	if _, err := c.Store.Get(getSha512Sum("https://www.dogbreedslist.info/049/")); err != nil {
+		t.Errorf("(fail) Did not expect the last response to be evicted. (err: %v)", err)
+	}
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy tells how the requests failed by a network error, a 5xx or a 429 response are
+// retried. Up to Attempts requests are made, waiting BaseDelay before the first retry, then
+// twice as long before every next one, but never more than MaxDelay. Every delay is shortened
+// by a random jitter of up to half of it, so the clients failing together do not retry
+// together. The delay asked by the Retry-After header of a response is waited instead when
+// it is longer, while the request is given up when it is longer than MaxDelay.
+//
+// The zero fields take the value of DefaultRetryPolicy, so a single attempt disables the
+// retries.
+type RetryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:  4,
+	BaseDelay: 500 * time.Millisecond,
+	MaxDelay:  30 * time.Second,
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Attempts <= 0 {
+		p.Attempts = DefaultRetryPolicy.Attempts
+	}
+
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+
+	return p
+}
+
+// backoff returns the delay before the retry following the attempt-th request.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	return delay - time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// retryAfter returns the delay asked by the Retry-After header of res, in seconds or as a
+// date, or zero when there is none.
+func retryAfter(res *http.Response) time.Duration {
+	if res == nil {
+		return 0
+	}
+
+	val := res.Header.Get("Retry-After")
+	if len(val) == 0 {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(val); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+
+	if at, err := http.ParseTime(val); err == nil {
+		return time.Until(at)
+	}
+
+	return 0
+}
+
+// fetchRetry fetches resUrl as told by the RetryPolicy of the cache. Only the successful
+// responses are returned, along with the 304 responses to the requests revalidating cached.
+func (c *Cache) fetchRetry(ctx context.Context, resUrl string, cached *CacheResponse) (*http.Response, error) {
+	policy := c.Retry.withDefaults()
+
+	for attempt := 1; ; attempt++ {
+		res, err := c.fetch(ctx, resUrl, cached)
+
+		var retry bool
+
+		switch {
+		case err != nil:
+			// The network errors are retried, unless they are caused by ctx or by a host which
+			// does not exist.
+			var dnsErr *net.DNSError
+			retry = ctx.Err() == nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound)
+		case res.StatusCode >= 200 && res.StatusCode < 300:
+			return res, nil
+		case res.StatusCode == http.StatusNotModified && cached != nil:
+			return res, nil
+		default:
+			drain(res.Body)
+			retry = res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+			err = &Error{Op: "fetch", URL: resUrl, Kind: ErrSourceUnavailable, Err: &StatusError{StatusCode: res.StatusCode, Status: res.Status}}
+		}
+
+		if !retry || attempt >= policy.Attempts {
+			return nil, err
+		}
+
+		delay := policy.backoff(attempt)
+		if after := retryAfter(res); after > policy.MaxDelay {
+			return nil, err
+		} else if after > delay {
+			delay = after
+		}
+
+		t := time.NewTimer(delay)
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, &Error{Op: "fetch", URL: resUrl, Kind: ErrSourceUnavailable, Err: ctx.Err()}
+		}
+	}
+}
+
+// drain reads what is left of a body so the connection can be reused, then closes it.
+func drain(body io.ReadCloser) {
+	io.Copy(ioutil.Discard, io.LimitReader(body, 1<<16))
+	body.Close()
+}
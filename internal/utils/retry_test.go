@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedTransport answers the requests with the statuses of its script in turn, a zero
+// status failing the request with a network error. The last status answers every request
+// past the script.
+type scriptedTransport struct {
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	requests int
+}
+
+func (st *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	st.mu.Lock()
+	status := st.statuses[len(st.statuses)-1]
+	if st.requests < len(st.statuses) {
+		status = st.statuses[st.requests]
+	}
+
+	st.requests++
+	st.mu.Unlock()
+
+	if status == 0 {
+		return nil, errors.New("connection reset by peer")
+	}
+
+	header := http.Header{}
+	for key, vals := range st.header {
+		header[key] = vals
+	}
+
+	return &http.Response{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader(http.StatusText(status) + " " + req.URL.String())),
+		Request:    req,
+	}, nil
+}
+
+func newScriptedCache(statuses ...int) (*Cache, *scriptedTransport) {
+	st := &scriptedTransport{statuses: statuses}
+	c := &Cache{
+		Store:  NewMemoryStore(),
+		Client: &http.Client{Transport: st},
+		Retry:  RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+	}
+
+	return c, st
+}
+
+func Test_NewCacheResponseRetry(t *testing.T) {
+	const resUrl = "https://www.dogbreedslist.info/"
+
+	tests := []struct {
+		name     string
+		statuses []int
+		requests int
+		ok       bool
+	}{
+		{name: "server errors", statuses: []int{500, 503, 200}, requests: 3, ok: true},
+		{name: "network error", statuses: []int{0, 200}, requests: 2, ok: true},
+		{name: "too many requests", statuses: []int{429, 200}, requests: 2, ok: true},
+		{name: "exhausted", statuses: []int{502}, requests: 3},
+		{name: "not found", statuses: []int{404, 200}, requests: 1},
+	}
+
+	for _, T := range tests {
+		t.Run(T.name, func(t *testing.T) {
+			c, st := newScriptedCache(T.statuses...)
+
+			res, _, err := c.NewCacheResponse(context.Background(), resUrl)
+			if st.requests != T.requests {
+				t.Errorf("(fail) Did not expect the number of requests. (output: %d, expected: %d)", st.requests, T.requests)
+			}
+
+			keys, _ := c.Store.List()
+
+			if !T.ok {
+				if !errors.Is(err, ErrSourceUnavailable) {
+					t.Errorf("(fail) Expected ErrSourceUnavailable from a failed request. (output: %v)", err)
+				}
+
+				if len(keys) != 0 {
+					t.Errorf("(fail) Did not expect a failed response to be cached. (output: %v)", keys)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("(fail) Unable to fetch the page. (err: %v)", err)
+			}
+
+			defer res.Body.Close()
+
+			if res.StatusCode != 200 || len(keys) != 1 {
+				t.Errorf("(fail) Expected the successful response to be cached. (output: %d, %v)", res.StatusCode, keys)
+			}
+		})
+	}
+}
+
+func Test_NewCacheResponseRetryAfter(t *testing.T) {
+	c, st := newScriptedCache(503, 200)
+	c.Retry.MaxDelay = 5 * time.Second
+	st.header = http.Header{"Retry-After": {"1"}}
+
+	start := time.Now()
+
+	res, _, err := c.NewCacheResponse(context.Background(), "https://www.dogbreedslist.info/")
+	if err != nil {
+		t.Fatalf("(fail) Unable to fetch the page. (err: %v)", err)
+	}
+
+	res.Body.Close()
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("(fail) Expected the retry to wait for the Retry-After delay. (elapsed: %v)", elapsed)
+	}
+
+	// A Retry-After delay longer than MaxDelay is not waited for.
+	c, st = newScriptedCache(429, 200)
+	st.header = http.Header{"Retry-After": {"3600"}}
+
+	if _, _, err := c.NewCacheResponse(context.Background(), "https://www.dogbreedslist.info/"); !errors.Is(err, ErrSourceUnavailable) || st.requests != 1 {
+		t.Errorf("(fail) Expected the request to be given up. (output: %v, %d requests)", err, st.requests)
+	}
+}
+
+func Test_NewCacheResponseRetryRevalidate(t *testing.T) {
+	const resUrl = "https://www.dogbreedslist.info/"
+
+	c, st := newScriptedCache(200, 500)
+	c.TTL = -1
+
+	res, key, err := c.NewCacheResponse(context.Background(), resUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	// The revalidation fails, so the cached page is used as it is and left as it was.
+	res, _, err = c.NewCacheResponse(context.Background(), resUrl)
+	if err != nil {
+		t.Fatalf("(fail) Expected the cached page from the failed revalidation. (err: %v)", err)
+	}
+
+	if P, _ := ioutil.ReadAll(res.Body); !strings.HasPrefix(string(P), "OK ") {
+		t.Errorf("(fail) Expected the cached page. (output: %q)", P)
+	}
+
+	res.Body.Close()
+
+	e, err := c.Store.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if P, _ := e.Decode(); !strings.HasPrefix(string(P), "OK ") || st.requests != 4 {
+		t.Errorf("(fail) Did not expect the cached page to be replaced. (output: %q, %d requests)", P, st.requests)
+	}
+}
@@ -0,0 +1,851 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore holds the cached responses, keyed by the sha512 sum of their url. Get and Stat
+// return an error wrapping ErrCacheMiss when there is no entry for a key.
+type CacheStore interface {
+	Get(key string) (*Entry, error)
+	Put(key string, e *Entry) error
+	Delete(key string) error
+	List() ([]string, error)
+	Stat(key string) (*EntryInfo, error)
+}
+
+// Peeker is implemented by the stores which can be looked into without being changed, for the
+// tools inspecting a cache. Peek, PeekStat and PeekList work like Get, Stat and List, except
+// that the access times of the entries are left as they were, the incomplete entries are
+// reported with ErrIncomplete rather than discarded, and nothing left behind is cleaned up.
+type Peeker interface {
+	Peek(key string) (*Entry, error)
+	PeekStat(key string) (*EntryInfo, error)
+	PeekList() ([]string, error)
+}
+
+// Entry is a cached response: its metadata, in a CacheResponse without a body, and the body.
+type Entry struct {
+	Meta *CacheResponse
+	Body []byte
+}
+
+// EntryInfo describes an entry without reading its body. Size is the size of the body as it is
+// stored, that is once encoded.
+//
+// URL is only known for the entries written since it was recorded.
+type EntryInfo struct {
+	Key        string
+	URL        string
+	Status     int
+	Codec      string
+	Size       int64
+	FetchedAt  time.Time
+	ExpiresAt  time.Time
+	AccessedAt time.Time
+}
+
+func (e *Entry) info(key string) *EntryInfo {
+	return e.Meta.info(key, int64(len(e.Body)))
+}
+
+func (c *CacheResponse) info(key string, size int64) *EntryInfo {
+	info := &EntryInfo{
+		Key:        key,
+		URL:        c.Res_url,
+		Codec:      c.Body_codec,
+		Size:       size,
+		FetchedAt:  c.FetchedAt(),
+		ExpiresAt:  c.E_at,
+		AccessedAt: time.Now(),
+	}
+
+	if c.Response != nil {
+		info.Status = c.StatusCode
+	}
+
+	if len(info.Codec) == 0 {
+		info.Codec = CodecIdentity
+	}
+
+	return info
+}
+
+// FileStore is the CacheStore keeping every entry in a pair of files under Dir: <key>.json
+// holds the metadata, and <key>.cache the body.
+//
+// Each file is written to a temporary file which is then renamed into place, while an
+// advisory file lock keeps other processes from reading or writing the entry meanwhile. The
+// locks are striped over at most 256 files under Dir/.locks, so they are never removed.
+//
+// The size and checksum of the body are recorded in the metadata, so an entry left
+// incomplete by a process which was killed, or by a system without file locks, is detected
+// and discarded when read.
+//
+// The modification time of <key>.json tells when the entry was last accessed.
+type FileStore struct {
+	Dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir}
+}
+
+// orphanedTempAge is the age from which a temporary file is considered left behind by a
+// process which did not complete its write.
+const orphanedTempAge = time.Hour
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.Dir, key)
+}
+
+func (s *FileStore) lock(key string) (unlock func(), err error) {
+	stripe := key
+	if len(stripe) > 2 {
+		stripe = stripe[:2]
+	}
+
+	dir := filepath.Join(s.Dir, ".locks")
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+
+	return lockFile(filepath.Join(dir, stripe+".lock"))
+}
+
+func (s *FileStore) readMeta(key string) (*CacheResponse, error) {
+	P, err := ioutil.ReadFile(s.path(key) + ".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrCacheMiss
+	} else if err != nil {
+		return nil, err
+	}
+
+	meta := &CacheResponse{}
+	if err := json.Unmarshal(P, meta); err != nil {
+		return nil, ErrIncomplete
+	}
+
+	meta.Cache_path = s.path(key)
+	return meta, nil
+}
+
+func (s *FileStore) readEntry(key string) (*Entry, error) {
+	meta, err := s.readMeta(key)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadFile(s.path(key) + ".cache")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrIncomplete
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(meta.Body_sum) != 0 && (int64(len(body)) != meta.Body_size || BodySum(body) != meta.Body_sum) {
+		return nil, ErrIncomplete
+	}
+
+	return &Entry{Meta: meta, Body: body}, nil
+}
+
+// Get discards the entry found incomplete, which is then reported as a miss.
+func (s *FileStore) Get(key string) (*Entry, error) {
+	if !s.exists(key) {
+		return nil, ErrCacheMiss
+	}
+
+	unlock, err := s.lock(key)
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	e, err := s.readEntry(key)
+	if errors.Is(err, ErrIncomplete) {
+		if err := s.remove(key); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrCacheMiss
+	} else if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	os.Chtimes(s.path(key)+".json", now, now)
+
+	return e, nil
+}
+
+// Put writes the body of the entry before its metadata, so an entry is not found until both
+// files are written.
+func (s *FileStore) Put(key string, e *Entry) error {
+	meta := *e.Meta
+	meta.Body_size = int64(len(e.Body))
+	meta.Body_sum = BodySum(e.Body)
+
+	P, err := marshalMeta(&meta)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := s.lock(key)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	if err := WriteFileAtomic(s.path(key)+".cache", e.Body); err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(s.path(key)+".json", P)
+}
+
+// WriteFileAtomic writes P into a temporary file next to path, then renames it to path, so
+// path is either left untouched or completely written.
+func WriteFileAtomic(path string, P []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(P)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chmod(f.Name(), 0640)
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
+
+// BodySum returns the sha256 sum of a body, which tells its changes apart.
+func BodySum(body []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(body))
+}
+
+// exists tells the keys which surely have no entry apart without locking them.
+func (s *FileStore) exists(key string) bool {
+	_, err := os.Stat(s.path(key) + ".json")
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+func (s *FileStore) Delete(key string) error {
+	if !s.exists(key) {
+		return nil
+	}
+
+	unlock, err := s.lock(key)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	return s.remove(key)
+}
+
+// remove deletes the files of an entry, the metadata first so the entry is not found once
+// its body is gone.
+func (s *FileStore) remove(key string) error {
+	for _, ext := range []string{".json", ".cache"} {
+		if err := os.Remove(s.path(key) + ext); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// List also removes the bodies left without metadata, and the temporary files left behind by
+// the processes which did not complete their writes.
+func (s *FileStore) List() ([]string, error) {
+	return s.list(true)
+}
+
+// list lists the keys of the entries, also removing what was left behind when clean is set.
+func (s *FileStore) list(clean bool) ([]string, error) {
+	files, err := ioutil.ReadDir(s.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for _, f := range files {
+		names[f.Name()] = true
+	}
+
+	var keys []string
+	for _, f := range files {
+		name := f.Name()
+
+		switch {
+		case f.IsDir():
+		case strings.HasSuffix(name, ".json"):
+			keys = append(keys, strings.TrimSuffix(name, ".json"))
+		case !clean:
+		case strings.HasSuffix(name, ".cache") && !names[strings.TrimSuffix(name, ".cache")+".json"]:
+			s.discardOrphan(strings.TrimSuffix(name, ".cache"))
+		case strings.HasSuffix(name, ".tmp") && time.Since(f.ModTime()) > orphanedTempAge:
+			os.Remove(filepath.Join(s.Dir, name))
+		}
+	}
+
+	return keys, nil
+}
+
+// discardOrphan removes the body of key unless its metadata was written since it was listed.
+func (s *FileStore) discardOrphan(key string) {
+	unlock, err := s.lock(key)
+	if err != nil {
+		return
+	}
+
+	defer unlock()
+
+	if _, err := os.Stat(s.path(key) + ".json"); errors.Is(err, fs.ErrNotExist) {
+		s.remove(key)
+	}
+}
+
+func (s *FileStore) Stat(key string) (*EntryInfo, error) {
+	if !s.exists(key) {
+		return nil, ErrCacheMiss
+	}
+
+	unlock, err := s.lock(key)
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	info, err := s.stat(key)
+	if errors.Is(err, ErrIncomplete) {
+		return nil, ErrCacheMiss
+	}
+
+	return info, err
+}
+
+func (s *FileStore) stat(key string) (*EntryInfo, error) {
+	meta, err := s.readMeta(key)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(s.path(key) + ".cache")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrIncomplete
+	} else if err != nil {
+		return nil, err
+	}
+
+	// The body is not read, so only its size is checked.
+	if len(meta.Body_sum) != 0 && fi.Size() != meta.Body_size {
+		return nil, ErrIncomplete
+	}
+
+	metaFi, err := os.Stat(s.path(key) + ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	info := meta.info(key, fi.Size())
+	info.AccessedAt = metaFi.ModTime()
+
+	return info, nil
+}
+
+// Peek does not lock the entry, which could then be reported incomplete while it is written.
+func (s *FileStore) Peek(key string) (*Entry, error) {
+	return s.readEntry(key)
+}
+
+func (s *FileStore) PeekStat(key string) (*EntryInfo, error) {
+	return s.stat(key)
+}
+
+func (s *FileStore) PeekList() ([]string, error) {
+	return s.list(false)
+}
+
+// MemoryStore is the CacheStore keeping its entries in memory, for the programs which cannot
+// or should not write to the disk. The entries are lost with the store.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	entries  map[string]*Entry
+	accessed map[string]time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*Entry), accessed: make(map[string]time.Time)}
+}
+
+func (s *MemoryStore) Get(key string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.entries[key]
+	if !exists {
+		return nil, ErrCacheMiss
+	}
+
+	s.accessed[key] = time.Now()
+	return e.clone(), nil
+}
+
+func (s *MemoryStore) Put(key string, e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = e.clone()
+	s.accessed[key] = time.Now()
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	delete(s.accessed, key)
+	return nil
+}
+
+func (s *MemoryStore) List() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *MemoryStore) Stat(key string) (*EntryInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, exists := s.entries[key]
+	if !exists {
+		return nil, ErrCacheMiss
+	}
+
+	info := e.info(key)
+	info.AccessedAt = s.accessed[key]
+	return info, nil
+}
+
+func (s *MemoryStore) Peek(key string) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, exists := s.entries[key]
+	if !exists {
+		return nil, ErrCacheMiss
+	}
+
+	return e.clone(), nil
+}
+
+func (s *MemoryStore) PeekStat(key string) (*EntryInfo, error) {
+	return s.Stat(key)
+}
+
+func (s *MemoryStore) PeekList() ([]string, error) {
+	return s.List()
+}
+
+// clone copies the entry, so the callers of the store cannot change the entries it holds.
+func (e *Entry) clone() *Entry {
+	meta := *e.Meta
+	if e.Meta.Response != nil {
+		res := *e.Meta.Response
+		res.Header = e.Meta.Header.Clone()
+		meta.Response = &res
+	}
+
+	return &Entry{Meta: &meta, Body: append([]byte(nil), e.Body...)}
+}
+
+// ArchiveStore is the CacheStore keeping every entry in a single file, to which the entries
+// are only ever appended, one JSON record per line. The last record of a key wins, and a
+// deleted key is recorded by a record without an entry. The records left behind by a write
+// which did not complete are dropped when the archive is opened, while Compact drops the ones
+// replaced or deleted since.
+//
+// The access times of the entries are only kept in memory, the time an entry was written
+// standing for it once the archive is reopened.
+type ArchiveStore struct {
+	mu       sync.Mutex
+	path     string
+	f        *os.File
+	size     int64
+	index    map[string]*archiveRecord
+	readOnly bool
+}
+
+type archiveLine struct {
+	Key  string          `json:"key"`
+	Meta json.RawMessage `json:"meta,omitempty"`
+	Body []byte          `json:"body,omitempty"`
+}
+
+type archiveRecord struct {
+	off, len int64
+	info     *EntryInfo
+}
+
+// OpenArchiveStore opens the archive at path, creating it if it does not exist yet. The
+// archive must be closed once it is no longer used.
+func OpenArchiveStore(path string) (*ArchiveStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ArchiveStore{path: path, f: f, index: make(map[string]*archiveRecord)}
+	if err := s.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// OpenArchiveStoreReadOnly opens the archive at path without changing it, for the tools
+// inspecting it: the archive is neither created nor rid of an incomplete record, and the
+// store fails to put or delete any entry.
+func OpenArchiveStoreReadOnly(path string) (*ArchiveStore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ArchiveStore{path: path, f: f, index: make(map[string]*archiveRecord), readOnly: true}
+	if err := s.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// errReadOnly is reported by an ArchiveStore opened read-only which is asked to change.
+var errReadOnly = errors.New("archive opened read-only")
+
+func (s *ArchiveStore) load() error {
+	r := bufio.NewReader(s.f)
+
+	for {
+		P, err := r.ReadBytes('\n')
+		if err != nil {
+			// A record without its line feed was not completely written.
+			break
+		}
+
+		line := &archiveLine{}
+		if err := json.Unmarshal(P, line); err != nil {
+			break
+		}
+
+		if line.Meta == nil {
+			delete(s.index, line.Key)
+		} else {
+			e, err := line.entry()
+			if err != nil {
+				break
+			}
+
+			info := e.info(line.Key)
+			info.AccessedAt = info.FetchedAt
+
+			s.index[line.Key] = &archiveRecord{off: s.size, len: int64(len(P)), info: info}
+		}
+
+		s.size += int64(len(P))
+	}
+
+	if s.readOnly {
+		return nil
+	}
+
+	if err := s.f.Truncate(s.size); err != nil {
+		return err
+	}
+
+	_, err := s.f.Seek(s.size, 0)
+	return err
+}
+
+func (line *archiveLine) entry() (*Entry, error) {
+	meta := &CacheResponse{}
+	if err := json.Unmarshal(line.Meta, meta); err != nil {
+		return nil, err
+	}
+
+	return &Entry{Meta: meta, Body: line.Body}, nil
+}
+
+func (s *ArchiveStore) append(line *archiveLine) (*archiveRecord, error) {
+	if s.readOnly {
+		return nil, errReadOnly
+	}
+
+	P, err := json.Marshal(line)
+	if err != nil {
+		return nil, err
+	}
+
+	P = append(P, '\n')
+
+	if _, err := s.f.WriteAt(P, s.size); err != nil {
+		// Drop whatever part of the record was written, so the next one starts on its own line.
+		s.f.Truncate(s.size)
+		return nil, err
+	}
+
+	rec := &archiveRecord{off: s.size, len: int64(len(P))}
+	s.size += rec.len
+
+	return rec, nil
+}
+
+func (s *ArchiveStore) Get(key string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.read(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.index[key].info.AccessedAt = time.Now()
+	return e, nil
+}
+
+func (s *ArchiveStore) Peek(key string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read(key)
+}
+
+func (s *ArchiveStore) read(key string) (*Entry, error) {
+	rec, exists := s.index[key]
+	if !exists {
+		return nil, ErrCacheMiss
+	}
+
+	P := make([]byte, rec.len)
+	if _, err := s.f.ReadAt(P, rec.off); err != nil {
+		return nil, err
+	}
+
+	line := &archiveLine{}
+	if err := json.Unmarshal(P, line); err != nil {
+		return nil, err
+	}
+
+	return line.entry()
+}
+
+func (s *ArchiveStore) Put(key string, e *Entry) error {
+	meta, err := marshalMeta(e.Meta)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.append(&archiveLine{Key: key, Meta: meta, Body: e.Body})
+	if err != nil {
+		return err
+	}
+
+	rec.info = e.info(key)
+	s.index[key] = rec
+	return nil
+}
+
+func (s *ArchiveStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.index[key]; !exists {
+		return nil
+	}
+
+	if _, err := s.append(&archiveLine{Key: key}); err != nil {
+		return err
+	}
+
+	delete(s.index, key)
+	return nil
+}
+
+func (s *ArchiveStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.index))
+	for key := range s.index {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *ArchiveStore) Stat(key string) (*EntryInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, exists := s.index[key]
+	if !exists {
+		return nil, ErrCacheMiss
+	}
+
+	info := *rec.info
+	return &info, nil
+}
+
+func (s *ArchiveStore) PeekStat(key string) (*EntryInfo, error) {
+	return s.Stat(key)
+}
+
+func (s *ArchiveStore) PeekList() ([]string, error) {
+	return s.List()
+}
+
+// Compact rewrites the archive with the last record of every entry only, which is then renamed
+// over the archive.
+func (s *ArchiveStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.readOnly {
+		return errReadOnly
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	keys := make([]string, 0, len(s.index))
+	for key := range s.index {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var size int64
+	offsets := make(map[string]int64, len(keys))
+
+	for _, key := range keys {
+		rec := s.index[key]
+
+		P := make([]byte, rec.len)
+		if _, err := s.f.ReadAt(P, rec.off); err != nil {
+			tmp.Close()
+			return err
+		}
+
+		if _, err := tmp.Write(P); err != nil {
+			tmp.Close()
+			return err
+		}
+
+		offsets[key] = size
+		size += rec.len
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0640); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	s.f.Close()
+	s.f = tmp
+	s.size = size
+
+	for key, off := range offsets {
+		s.index[key].off = off
+	}
+
+	return nil
+}
+
+func (s *ArchiveStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.f.Close()
+}
+
+// marshalMeta encodes the metadata of a response, that is everything but its body.
+func marshalMeta(cache *CacheResponse) ([]byte, error) {
+	meta := *cache
+	meta.Cache_path = ""
+
+	if cache.Response != nil {
+		res := *cache.Response
+		res.Body = nil
+		res.Request = nil
+		res.TLS = nil
+		meta.Response = &res
+	}
+
+	return json.Marshal(meta)
+}
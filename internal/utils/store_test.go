@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testEntry(body string) *Entry {
+	return &Entry{
+		Meta: &CacheResponse{
+			E_at:     time.Now().Add(time.Hour).Round(0),
+			F_at:     time.Now().Round(0),
+			Response: &http.Response{StatusCode: 200, Header: http.Header{"Etag": {`"` + body + `"`}}},
+		},
+		Body: []byte(body),
+	}
+}
+
+func testCacheStore(t *testing.T, store CacheStore) {
+	if _, err := store.Get("a"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("(fail) Expected a cache miss. (err: %v)", err)
+	}
+
+	if _, err := store.Stat("a"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("(fail) Expected a cache miss. (err: %v)", err)
+	}
+
+	for _, key := range []string{"b", "a", "c"} {
+		if err := store.Put(key, testEntry("body "+key)); err != nil {
+			t.Fatalf("(fail) Unable to put the entry: %s (err: %v)", key, err)
+		}
+	}
+
+	// The last entry put under a key wins.
+	if err := store.Put("a", testEntry("new body a")); err != nil {
+		t.Fatalf("(fail) Unable to put the entry: a (err: %v)", err)
+	}
+
+	e, err := store.Get("a")
+	if err != nil || string(e.Body) != "new body a" || e.Meta.StatusCode != 200 || e.Meta.Header.Get("ETag") != `"new body a"` {
+		t.Errorf("(fail) Did not get the entry back. (output: %+v, err: %v)", e, err)
+	}
+
+	info, err := store.Stat("a")
+	if err != nil || info.Key != "a" || info.Size != int64(len("new body a")) || !info.ExpiresAt.Equal(e.Meta.E_at) {
+		t.Errorf("(fail) Did not describe the entry. (output: %+v, err: %v)", info, err)
+	}
+
+	if err := store.Delete("b"); err != nil {
+		t.Errorf("(fail) Unable to delete the entry. (err: %v)", err)
+	}
+
+	if err := store.Delete("missing"); err != nil {
+		t.Errorf("(fail) Did not expect an error deleting a missing entry. (err: %v)", err)
+	}
+
+	keys, err := store.List()
+	if err != nil || len(keys) != 2 || keys[0] != "a" || keys[1] != "c" {
+		t.Errorf("(fail) Did not list the entries. (output: %v, err: %v)", keys, err)
+	}
+}
+
+func Test_FileStore(t *testing.T) {
+	testCacheStore(t, NewFileStore(t.TempDir()))
+}
+
+func Test_MemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	testCacheStore(t, store)
+
+	// Changing an entry which was read does not change the store.
+	e, _ := store.Get("c")
+	e.Body[0] = 'B'
+
+	if e, _ := store.Get("c"); string(e.Body) != "body c" {
+		t.Errorf("(fail) Did not expect the entry to change. (output: %s)", e.Body)
+	}
+}
+
+func Test_ArchiveStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.archive")
+
+	store, err := OpenArchiveStore(path)
+	if err != nil {
+		t.Fatalf("(fail) Unable to open the archive. (err: %v)", err)
+	}
+
+	testCacheStore(t, store)
+	store.Close()
+
+	// Simulate a write which did not complete.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Write([]byte(`{"key":"d","meta":{"E_a`))
+	f.Close()
+
+	store, err = OpenArchiveStore(path)
+	if err != nil {
+		t.Fatalf("(fail) Unable to reopen the archive. (err: %v)", err)
+	}
+
+	defer store.Close()
+
+	keys, _ := store.List()
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "c" {
+		t.Errorf("(fail) Did not read the entries back from the archive. (output: %v)", keys)
+	}
+
+	if e, err := store.Get("a"); err != nil || string(e.Body) != "new body a" {
+		t.Errorf("(fail) Did not read the last entry of a key. (output: %v, err: %v)", e, err)
+	}
+
+	if err := store.Put("d", testEntry("body d")); err != nil {
+		t.Fatalf("(fail) Unable to put the entry after the incomplete one. (err: %v)", err)
+	}
+
+	if e, err := store.Get("d"); err != nil || string(e.Body) != "body d" {
+		t.Errorf("(fail) Did not get the entry back. (output: %v, err: %v)", e, err)
+	}
+}
+
+func Test_FileStorePeek(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	for _, key := range []string{"a", "b"} {
+		if err := store.Put(key, testEntry("body "+key)); err != nil {
+			t.Fatalf("(fail) Unable to put the entry: %s (err: %v)", key, err)
+		}
+	}
+
+	accessed := time.Now().Add(-time.Hour).Round(time.Second)
+	os.Chtimes(filepath.Join(store.Dir, "a.json"), accessed, accessed)
+
+	// b is left incomplete, along with a body without metadata and an old temporary file.
+	os.Truncate(filepath.Join(store.Dir, "b.cache"), 2)
+
+	orphan := filepath.Join(store.Dir, "orphan")
+	ioutil.WriteFile(orphan+".cache", []byte("orphaned body"), 0640)
+	ioutil.WriteFile(orphan+".cache.123.tmp", []byte("orphaned"), 0640)
+	os.Chtimes(orphan+".cache.123.tmp", time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour))
+
+	if keys, err := store.PeekList(); err != nil || len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("(fail) Did not list the expected entries. (output: %v, err: %v)", keys, err)
+	}
+
+	if e, err := store.Peek("a"); err != nil || string(e.Body) != "body a" {
+		t.Errorf("(fail) Did not peek the entry. (output: %v, err: %v)", e, err)
+	}
+
+	if info, err := store.PeekStat("a"); err != nil || !info.AccessedAt.Equal(accessed) {
+		t.Errorf("(fail) Did not expect the access time to change. (output: %+v, err: %v)", info, err)
+	}
+
+	if _, err := store.Peek("b"); !errors.Is(err, ErrIncomplete) {
+		t.Errorf("(fail) Expected the entry to be reported incomplete. (err: %v)", err)
+	}
+
+	if _, err := store.Peek("missing"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("(fail) Expected a cache miss. (err: %v)", err)
+	}
+
+	// Nothing was removed.
+	for _, name := range []string{"b.json", "b.cache", "orphan.cache", "orphan.cache.123.tmp"} {
+		if _, err := os.Stat(filepath.Join(store.Dir, name)); err != nil {
+			t.Errorf("(fail) Did not expect the file to be removed: %s (err: %v)", name, err)
+		}
+	}
+}
+
+func Test_ArchiveStoreReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.archive")
+
+	if _, err := OpenArchiveStoreReadOnly(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("(fail) Expected a missing archive to fail. (err: %v)", err)
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("(fail) Did not expect the archive to be created.")
+	}
+
+	store, err := OpenArchiveStore(path)
+	if err != nil {
+		t.Fatalf("(fail) Unable to open the archive. (err: %v)", err)
+	}
+
+	store.Put("a", testEntry("body a"))
+	store.Close()
+
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write([]byte(`{"key":"b","meta":{"E_a`))
+	f.Close()
+
+	fi, _ := os.Stat(path)
+
+	store, err = OpenArchiveStoreReadOnly(path)
+	if err != nil {
+		t.Fatalf("(fail) Unable to open the archive read-only. (err: %v)", err)
+	}
+
+	defer store.Close()
+
+	if e, err := store.Peek("a"); err != nil || string(e.Body) != "body a" {
+		t.Errorf("(fail) Did not peek the entry. (output: %v, err: %v)", e, err)
+	}
+
+	if err := store.Put("c", testEntry("body c")); err == nil {
+		t.Errorf("(fail) Expected the read-only archive to fail to put an entry.")
+	}
+
+	if err := store.Delete("a"); err == nil {
+		t.Errorf("(fail) Expected the read-only archive to fail to delete an entry.")
+	}
+
+	if err := store.Compact(); err == nil {
+		t.Errorf("(fail) Expected the read-only archive to fail to compact.")
+	}
+
+	if after, _ := os.Stat(path); after.Size() != fi.Size() {
+		t.Errorf("(fail) Did not expect the archive to change. (output: %d bytes, expected: %d)", after.Size(), fi.Size())
+	}
+}
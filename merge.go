@@ -0,0 +1,318 @@
+package dogfetch
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Provenance tells where the value of a field of a breed comes from.
+type Provenance struct {
+	Source    BreedSource `json:"source"`
+	URL       string      `json:"url"`
+	FetchedAt time.Time   `json:"fetchedAt"`
+}
+
+// record is a breed extracted from a single page of a source.
+type record struct {
+	source    BreedSource
+	url       string
+	fetchedAt time.Time
+	breed     *BreedInfo
+
+	// etag and sum tell the changes of the page apart, while reused tells the records kept
+	// from the previous snapshot of an incremental crawl.
+	etag   string
+	sum    string
+	reused bool
+
+	// attachOnly tells the records of an Attacher source, which only complete the records of
+	// the other sources.
+	attachOnly bool
+}
+
+func (r *record) provenance() Provenance {
+	return Provenance{Source: r.source, URL: r.url, FetchedAt: r.fetchedAt}
+}
+
+// mergeRecords reconciles the breeds extracted from several sources. Records of different
+// sources are matched when the name of one of them is the name, or one of the other names, of
+// the other, while the records of the same source are never matched together. The list
+// fields of matching records are merged together, while the scalar fields are taken from the
+// record of the source coming first in priority. Sources missing from priority come last.
+// The groups made of attach-only records alone are dropped, and returned as unmatched.
+func mergeRecords(records []*record, priority []BreedSource) (merged BreedInfos, unmatched []*record) {
+	rank := make(map[BreedSource]int)
+	for i, src := range priority {
+		if _, exists := rank[src]; !exists {
+			rank[src] = i
+		}
+	}
+
+	rankOf := func(r *record) int {
+		if i, exists := rank[r.source]; exists {
+			return i
+		}
+
+		return len(priority)
+	}
+
+	records = append([]*record{}, records...)
+	sort.SliceStable(records, func(i, j int) bool {
+		ri, rj := rankOf(records[i]), rankOf(records[j])
+		if ri != rj {
+			return ri < rj
+		}
+
+		return records[i].breed.Id < records[j].breed.Id
+	})
+
+	parent := make([]int, len(records))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+
+		return parent[i]
+	}
+
+	// sources holds the sources of the records of every group, by its root. Two groups are
+	// only joined when no source has a record in both, so the breeds of a single source are
+	// never merged together, whatever their other names.
+	sources := make([]map[BreedSource]bool, len(records))
+	for i, r := range records {
+		sources[i] = map[BreedSource]bool{r.source: true}
+	}
+
+	union := func(i, j int) {
+		ri, rj := find(i), find(j)
+		if ri == rj {
+			return
+		}
+
+		for src := range sources[rj] {
+			if sources[ri][src] {
+				return
+			}
+		}
+
+		if rj < ri {
+			ri, rj = rj, ri
+		}
+
+		parent[rj] = ri
+		for src := range sources[rj] {
+			sources[ri][src] = true
+		}
+	}
+
+	byName := make(map[string][]int)
+	byOtherName := make(map[string][]int)
+
+	for i, r := range records {
+		if name := normalizeName(r.breed.Name); len(name) != 0 {
+			byName[name] = append(byName[name], i)
+		}
+
+		for _, otherName := range r.breed.OtherNames {
+			if name := normalizeName(otherName); len(name) != 0 {
+				byOtherName[name] = append(byOtherName[name], i)
+			}
+		}
+	}
+
+	// The records of the same name are matched first, so a breed is not taken by another one
+	// of the same source which only lists its name among its other names.
+	for _, r := range records {
+		group := byName[normalizeName(r.breed.Name)]
+		if len(group) < 2 {
+			continue
+		}
+
+		for _, j := range group[1:] {
+			union(group[0], j)
+		}
+	}
+
+	for i, r := range records {
+		for _, j := range byOtherName[normalizeName(r.breed.Name)] {
+			union(i, j)
+		}
+
+		for _, otherName := range r.breed.OtherNames {
+			for _, j := range byName[normalizeName(otherName)] {
+				union(i, j)
+			}
+		}
+	}
+
+	groups := make(map[int][]*record)
+	roots := []int{}
+
+	for i, r := range records {
+		root := find(i)
+		if _, exists := groups[root]; !exists {
+			roots = append(roots, root)
+		}
+
+		groups[root] = append(groups[root], r)
+	}
+
+	merged = make(BreedInfos)
+	for _, root := range roots {
+		if group := groups[root]; attachedOnly(group) {
+			unmatched = append(unmatched, group...)
+			continue
+		}
+
+		bi := mergeGroup(groups[root])
+		merged[bi.Id] = bi
+	}
+
+	return merged, unmatched
+}
+
+func attachedOnly(group []*record) bool {
+	for _, r := range group {
+		if !r.attachOnly {
+			return false
+		}
+	}
+
+	return true
+}
+
+// mergeGroup merges matching records, ordered by the priority of their source, into a single
+// breed, recording the provenance of each of its fields.
+func mergeGroup(group []*record) *BreedInfo {
+	bi := &BreedInfo{
+		Id:         group[0].breed.Id,
+		BreedChars: make(map[string]int64),
+		Refs:       make(map[string]any),
+		Provenance: make(map[string][]Provenance),
+	}
+
+	for _, r := range group {
+		src, p := r.breed, r.provenance()
+
+		scalar := func(field string, dst *string, val string) {
+			if len(strings.TrimSpace(*dst)) == 0 && len(strings.TrimSpace(val)) != 0 {
+				*dst = val
+				bi.Provenance[field] = []Provenance{p}
+			}
+		}
+
+		first := func(field string, dst *[]string, vals []string) {
+			if len(*dst) == 0 && len(vals) != 0 {
+				*dst = append([]string{}, vals...)
+				bi.Provenance[field] = []Provenance{p}
+			}
+		}
+
+		bounds := func(field string, dst *[]uint64, vals []uint64) {
+			if isZeroRange(*dst) && !isZeroRange(vals) {
+				*dst = append([]uint64{}, vals...)
+				bi.Provenance[field] = []Provenance{p}
+			}
+		}
+
+		list := func(field string, dst *[]string, vals []string) {
+			added := false
+			for _, val := range vals {
+				if len(strings.TrimSpace(val)) == 0 || containsName(*dst, val) {
+					continue
+				}
+
+				*dst = append(*dst, val)
+				added = true
+			}
+
+			if added {
+				bi.Provenance[field] = append(bi.Provenance[field], p)
+			}
+		}
+
+		scalar("name", &bi.Name, src.Name)
+		scalar("history", &bi.History, src.History)
+		scalar("type", &bi.Type, src.Type)
+		first("size", &bi.Size, src.Size)
+		first("breedGroups", &bi.BreedGroups, src.BreedGroups)
+		bounds("lifeSpan", &bi.Lifespan, src.Lifespan)
+		bounds("litterSize", &bi.LitterSize, src.LitterSize)
+		list("origins", &bi.Origin, src.Origin)
+		list("colors", &bi.Colors, src.Colors)
+		list("temperaments", &bi.Temperaments, src.Temperaments)
+
+		// The names given to the breed by the other sources are kept among its other names.
+		otherNames := []string{}
+		for _, name := range append([]string{src.Name}, src.OtherNames...) {
+			if normalizeName(name) != normalizeName(bi.Name) {
+				otherNames = append(otherNames, name)
+			}
+		}
+
+		list("otherNames", &bi.OtherNames, otherNames)
+		list("images", &bi.Images, src.Images)
+		list("breedRecs", &bi.BreedRecs, src.BreedRecs)
+
+		if bi.KennelClub == nil && src.KennelClub != nil {
+			bi.KennelClub = src.KennelClub
+			bi.Provenance["kennelClub"] = []Provenance{p}
+		}
+
+		added := false
+		for char, score := range src.BreedChars {
+			if _, exists := bi.BreedChars[char]; !exists {
+				bi.BreedChars[char] = score
+				added = true
+			}
+		}
+
+		if added {
+			bi.Provenance["breedChars"] = append(bi.Provenance["breedChars"], p)
+		}
+
+		added = false
+		for href, data := range src.Refs {
+			if _, exists := bi.Refs[href]; !exists {
+				bi.Refs[href] = data
+				added = true
+			}
+		}
+
+		if added {
+			bi.Provenance["refs"] = append(bi.Provenance["refs"], p)
+		}
+	}
+
+	return bi
+}
+
+func isZeroRange(vals []uint64) bool {
+	for _, n := range vals {
+		if n != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if normalizeName(n) == normalizeName(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
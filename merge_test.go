@@ -0,0 +1,115 @@
+package dogfetch_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rommms07/dogfetch"
+	"github.com/rommms07/dogfetch/internal/utils"
+)
+
+func Test_mergeRecords_priority(t *testing.T) {
+	dblSrv := newFixtureServer(t, dogBreedsListRoutes)
+	idSrv := newFixtureServer(t, infoDogsRoutes)
+
+	dbl := &dogfetch.DogBreedsList{BaseURL: dblSrv.URL}
+	id := &dogfetch.InfoDogs{BaseURL: idSrv.URL}
+
+	c := newTestClient(t, dogfetch.WithSources(dbl, id), dogfetch.WithSourcePriority(id.Name()))
+
+	dogs, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+	}
+
+	york := dogs[utils.GetMd5Sum("/breeds/yorkshire-terrier/")]
+	if york == nil {
+		t.Fatalf("(fail) Did not keep the breed of the source coming first in priority.")
+	}
+
+	if york.History != "The breed was developed by Yorkshire mill workers in the 1800s." {
+		t.Errorf("(fail) Did not take the history from the first source in priority. (output: %v)", york.History)
+	}
+
+	if len(york.Lifespan) != 2 || york.Lifespan[0] != 13 || york.Lifespan[1] != 16 {
+		t.Errorf("(fail) Did not take the life span from the first source in priority. (output: %v)", york.Lifespan)
+	}
+
+	if len(york.Origin) != 2 || york.Origin[0] != "England" || york.Origin[1] != "United Kingdom" {
+		t.Errorf("(fail) Did not merge the origins of both sources. (output: %v)", york.Origin)
+	}
+
+	lifespan := york.Provenance["lifeSpan"]
+	if len(lifespan) != 1 || lifespan[0].Source != id.Name() || lifespan[0].URL != idSrv.URL+"/breeds/yorkshire-terrier/" {
+		t.Errorf("(fail) Did not record the provenance of the life span. (output: %+v)", lifespan)
+	}
+
+	if lifespan[0].FetchedAt.IsZero() {
+		t.Errorf("(fail) Did not record when the page was fetched.")
+	}
+
+	origins := york.Provenance["origins"]
+	if len(origins) != 2 || origins[0].Source != id.Name() || origins[1].Source != dbl.Name() {
+		t.Errorf("(fail) Did not record the provenance of the merged origins. (output: %+v)", origins)
+	}
+}
+
+func Test_mergeRecords_otherNames(t *testing.T) {
+	idSrv := newFixtureServer(t, infoDogsRoutes)
+	kcSrv := newFixtureServer(t, kennelClubRoutes)
+
+	kc := &dogfetch.KennelClub{BaseURL: kcSrv.URL}
+	c := newTestClient(t, dogfetch.WithSources(&dogfetch.InfoDogs{BaseURL: idSrv.URL}, kc))
+
+	dogs, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+	}
+
+	collie := dogs[utils.GetMd5Sum("/breeds/border-collie/")]
+	if collie == nil {
+		t.Fatalf("(fail) Did not merge the breed found on both sources.")
+	}
+
+	for _, name := range collie.OtherNames {
+		if name == collie.Name {
+			t.Errorf("(fail) Did not expect the name of the breed among its other names. (output: %v)", collie.OtherNames)
+		}
+	}
+
+	if len(collie.BreedGroups) != 1 || collie.BreedGroups[0] != "Pastoral" {
+		t.Errorf("(fail) Did not keep the breed groups of the first source. (output: %v)", collie.BreedGroups)
+	}
+
+	if p := collie.Provenance["kennelClub"]; len(p) != 1 || p[0].Source != kc.Name() {
+		t.Errorf("(fail) Did not record the provenance of the classification. (output: %+v)", p)
+	}
+}
+
+func Test_mergeRecords_sameSource(t *testing.T) {
+	// The Belgian Shepherd lists the names of the Groenendael and the Malinois among its other
+	// names, yet they are breeds of their own on the same source.
+	srv := newFixtureServer(t, map[string]string{
+		"/dog-breeds-a-z/":                      "dogbreedslist/belgian/dog-breeds-a-z.html",
+		"/all-dog-breeds/belgian-shepherd.html": "dogbreedslist/belgian/belgian-shepherd.html",
+		"/all-dog-breeds/groenendael.html":      "dogbreedslist/belgian/groenendael.html",
+		"/all-dog-breeds/malinois.html":         "dogbreedslist/belgian/malinois.html",
+	})
+
+	c := newTestClient(t, dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}))
+
+	dogs, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+	}
+
+	if len(dogs) != 3 {
+		t.Errorf("(fail) Expected number of dogs did not matched! (output: %d)", len(dogs))
+	}
+
+	for _, path := range []string{"/all-dog-breeds/belgian-shepherd.html", "/all-dog-breeds/groenendael.html", "/all-dog-breeds/malinois.html"} {
+		if bi := dogs[utils.GetMd5Sum(path)]; bi == nil || len(bi.Provenance["name"]) != 1 {
+			t.Errorf("(fail) Expected the breed to be kept on its own: %s (output: %+v)", path, bi)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package dogfetch
+
+import "context"
+
+// Event is a progress event of a crawl, given to the function set by WithProgress. It is one
+// of the *Crawl..., *ListPageFetched, *BreedPage..., *ReferenceFetched or *Cache... events.
+type Event interface {
+	event()
+}
+
+// CrawlStarted is sent when a crawl starts.
+type CrawlStarted struct {
+	Sources []BreedSource
+}
+
+// CrawlFinished is sent when a crawl is over, along with its report.
+type CrawlFinished struct {
+	Report *CrawlReport
+	Err    error
+}
+
+// ListPageFetched is sent for every page fetched by a source while discovering its breed
+// pages.
+type ListPageFetched struct {
+	Source BreedSource
+	URL    string
+}
+
+// BreedPagesQueued is sent once the breed pages of a source are discovered, Count of them
+// being crawled next.
+type BreedPagesQueued struct {
+	Source BreedSource
+	Count  int
+}
+
+// BreedPageStarted is sent when a breed page starts being crawled.
+type BreedPageStarted struct {
+	Source BreedSource
+	URL    string
+}
+
+// BreedPageFinished is sent when a breed page is crawled. The references of the breed may
+// still be fetched afterwards. Reused tells whether the page was unchanged since the previous
+// snapshot, its breed being kept as it was.
+type BreedPageFinished struct {
+	Source BreedSource
+	URL    string
+	Reused bool
+}
+
+// BreedPageFailed is sent when a breed page could not be crawled. Err is of kind ErrBlocked
+// for a page disallowed by robots.txt.
+type BreedPageFailed struct {
+	Source BreedSource
+	URL    string
+	Err    error
+}
+
+// ReferenceFetched is sent for every reference of a breed once it is fetched, or once it
+// failed to be fetched, in which case Err tells why.
+type ReferenceFetched struct {
+	URL string
+	Err error
+}
+
+// CacheHit is sent for every response read from the cache, the ones revalidated with their
+// server included.
+type CacheHit struct {
+	URL string
+}
+
+// CacheMiss is sent for every response downloaded and stored into the cache.
+type CacheMiss struct {
+	URL string
+}
+
+func (*CrawlStarted) event()      {}
+func (*CrawlFinished) event()     {}
+func (*ListPageFetched) event()   {}
+func (*BreedPagesQueued) event()  {}
+func (*BreedPageStarted) event()  {}
+func (*BreedPageFinished) event() {}
+func (*BreedPageFailed) event()   {}
+func (*ReferenceFetched) event()  {}
+func (*CacheHit) event()          {}
+func (*CacheMiss) event()         {}
+
+// WithProgress sets the function to which the progress events of the crawls are sent. It is
+// called from the goroutines of the crawl, one event at a time, so it must return quickly.
+// ProgressChan turns the events into a channel.
+func WithProgress(fn func(Event)) Option {
+	return func(c *Client) {
+		c.progress = fn
+	}
+}
+
+// ProgressChan returns a function to give WithProgress, which sends the events into ch.
+// The events are dropped while ch is full, rather than slowing the crawl down.
+func ProgressChan(ch chan<- Event) func(Event) {
+	return func(e Event) {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// emit sends e to the progress function of the client, if any.
+func (c *Client) emit(e Event) {
+	if c.progress == nil {
+		return
+	}
+
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+
+	c.progress(e)
+}
+
+// listFetcher is the Fetcher given to the sources to discover their breed pages, which
+// reports the pages fetched.
+type listFetcher struct {
+	cr  *crawler
+	src Source
+}
+
+func (lf listFetcher) Fetch(ctx context.Context, pageUrl string) (*Page, error) {
+	page, err := lf.cr.Fetch(ctx, pageUrl)
+	if err == nil {
+		lf.cr.emit(&ListPageFetched{Source: lf.src.Name(), URL: pageUrl})
+	}
+
+	return page, err
+}
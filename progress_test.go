@@ -0,0 +1,94 @@
+package dogfetch_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rommms07/dogfetch"
+)
+
+func Test_Client_WithProgress(t *testing.T) {
+	srv := newFixtureServer(t, dogBreedsListRoutes)
+	store := dogfetch.NewMemoryStore()
+
+	crawl := func() []dogfetch.Event {
+		var events []dogfetch.Event
+		c := dogfetch.New(dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}), dogfetch.WithCacheStore(store),
+			dogfetch.WithProgress(func(e dogfetch.Event) { events = append(events, e) }))
+
+		if _, err := c.Crawl(context.Background()); err != nil {
+			t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+		}
+
+		return events
+	}
+
+	count := func(events []dogfetch.Event) map[string]int {
+		counts := make(map[string]int)
+		for _, e := range events {
+			switch e := e.(type) {
+			case *dogfetch.CrawlStarted:
+				counts["started"]++
+			case *dogfetch.ListPageFetched:
+				counts["list"]++
+			case *dogfetch.BreedPagesQueued:
+				counts["queued"] += e.Count
+			case *dogfetch.BreedPageStarted:
+				counts["page started"]++
+			case *dogfetch.BreedPageFinished:
+				counts["page finished"]++
+			case *dogfetch.BreedPageFailed:
+				counts["page failed"]++
+			case *dogfetch.ReferenceFetched:
+				counts["refs"]++
+			case *dogfetch.CacheHit:
+				counts["hits"]++
+			case *dogfetch.CacheMiss:
+				counts["misses"]++
+			case *dogfetch.CrawlFinished:
+				counts["finished"]++
+			}
+		}
+
+		return counts
+	}
+
+	events := crawl()
+	if _, first := events[0].(*dogfetch.CrawlStarted); !first {
+		t.Errorf("(fail) Expected the crawl to start with a CrawlStarted event. (output: %T)", events[0])
+	}
+
+	last, isLast := events[len(events)-1].(*dogfetch.CrawlFinished)
+	if !isLast || last.Report == nil || last.Report.Pages != 2 || last.Err != nil {
+		t.Errorf("(fail) Expected the crawl to end with a CrawlFinished event. (output: %+v)", events[len(events)-1])
+	}
+
+	counts := count(events)
+	for name, least := range map[string]int{"list": 1, "queued": 2, "page started": 2, "page finished": 2, "refs": 2, "misses": 4} {
+		if counts[name] < least {
+			t.Errorf("(fail) Expected at least %d %s events. (output: %v)", least, name, counts)
+		}
+	}
+
+	if counts["page failed"] != 0 {
+		t.Errorf("(fail) Did not expect failed pages. (output: %v)", counts)
+	}
+
+	// The pages are read back from the cache by the next crawl.
+	if counts := count(crawl()); counts["hits"] < 4 || counts["misses"] != 0 {
+		t.Errorf("(fail) Expected the pages to be read from the cache. (output: %v)", counts)
+	}
+}
+
+func Test_ProgressChan(t *testing.T) {
+	ch := make(chan dogfetch.Event, 1)
+	send := dogfetch.ProgressChan(ch)
+
+	// The second event is dropped rather than blocking.
+	send(&dogfetch.CacheHit{URL: "a"})
+	send(&dogfetch.CacheHit{URL: "b"})
+
+	if e := <-ch; e.(*dogfetch.CacheHit).URL != "a" || len(ch) != 0 {
+		t.Errorf("(fail) Did not expect the events of the channel. (output: %+v)", e)
+	}
+}
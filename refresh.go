@@ -0,0 +1,130 @@
+package dogfetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+)
+
+// RefreshSummary tells what an incremental refresh changed in the dataset of a client. Breeds
+// are listed by id, and pages by url.
+type RefreshSummary struct {
+	Added   []string `json:"added"`
+	Changed []string `json:"changed"`
+	Removed []string `json:"removed"`
+
+	// FetchedPages are the new or changed pages which were extracted again, while the breeds
+	// of the UnchangedPages were kept. RemovedPages are no longer listed by their source.
+	FetchedPages   []string `json:"fetchedPages"`
+	UnchangedPages int      `json:"unchangedPages"`
+	RemovedPages   []string `json:"removedPages"`
+}
+
+// RefreshIncremental refreshes the dataset of the client like Refresh, extracting only the
+// breed pages which are new or changed since the previous snapshot. A page is unchanged when
+// its ETag or the hash of its body are the same. The pages no longer listed by their source
+// are dropped along with their breeds, while the breeds of the unchanged pages are kept as
+// they are.
+//
+// The previous snapshot is the dataset of the client once loaded, or else its saved snapshot.
+// Every page is extracted when there is neither, or when its breeds were extracted otherwise
+// than the client would, by other rules or by another version of dogfetch. The dataset is left untouched if ctx is done
+// before the crawl is finished, or if any of the breed pages could not be crawled.
+func (c *Client) RefreshIncremental(ctx context.Context) (*RefreshSummary, error) {
+	prev, err := c.previousSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	cr := newCrawler(c)
+	if prev.Header.Extractor == c.extractor() {
+		cr.prev = prev.Pages
+	}
+
+	breeds, err := cr.run(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The merged breeds holding the same data are kept as they were, along with their
+	// provenance.
+	for id, bi := range breeds {
+		if old := prev.Breeds[id]; old != nil && sameBreed(old, bi) {
+			breeds[id] = old
+		}
+	}
+
+	s := c.newSnapshot(breeds, cr.pages())
+	sum := summarize(prev, s, cr.records)
+
+	c.set(s)
+	return sum, c.saveSnapshotFile(s)
+}
+
+func (c *Client) previousSnapshot() (*Snapshot, error) {
+	c.mu.RLock()
+	loaded := c.loaded
+	c.mu.RUnlock()
+
+	if loaded {
+		return c.Snapshot(), nil
+	}
+
+	s, err := c.savedSnapshot()
+	if err != nil || s != nil {
+		return s, err
+	}
+
+	return &Snapshot{Breeds: make(BreedInfos)}, nil
+}
+
+// summarize tells the changes from prev to next, crawled from records.
+func summarize(prev, next *Snapshot, records []*record) *RefreshSummary {
+	sum := &RefreshSummary{}
+
+	for _, r := range records {
+		if r.reused {
+			sum.UnchangedPages++
+		} else {
+			sum.FetchedPages = append(sum.FetchedPages, r.url)
+		}
+	}
+
+	for pageUrl := range prev.Pages {
+		if next.Pages[pageUrl] == nil {
+			sum.RemovedPages = append(sum.RemovedPages, pageUrl)
+		}
+	}
+
+	for id, bi := range next.Breeds {
+		if old := prev.Breeds[id]; old == nil {
+			sum.Added = append(sum.Added, id)
+		} else if !sameBreed(old, bi) {
+			sum.Changed = append(sum.Changed, id)
+		}
+	}
+
+	for id := range prev.Breeds {
+		if next.Breeds[id] == nil {
+			sum.Removed = append(sum.Removed, id)
+		}
+	}
+
+	for _, ids := range [][]string{sum.Added, sum.Changed, sum.Removed, sum.FetchedPages, sum.RemovedPages} {
+		sort.Strings(ids)
+	}
+
+	return sum
+}
+
+// sameBreed tells whether two breeds hold the same data, whenever they were fetched.
+func sameBreed(a, b *BreedInfo) bool {
+	ac, bc := *a, *b
+	ac.Provenance, bc.Provenance = nil, nil
+
+	P, errA := json.Marshal(&ac)
+	Q, errB := json.Marshal(&bc)
+
+	return errA == nil && errB == nil && bytes.Equal(P, Q)
+}
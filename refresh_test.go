@@ -0,0 +1,172 @@
+package dogfetch_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rommms07/dogfetch"
+)
+
+func Test_Client_RefreshIncremental(t *testing.T) {
+	routes := make(map[string]string)
+	for path, fixture := range dogBreedsListRoutes {
+		routes[path] = fixture
+	}
+
+	routes["/dog-breeds-a-z/"] = "dogbreedslist/refresh/a-z-australian.html"
+	srv := newFixtureServer(t, routes)
+
+	australian := srv.URL + "/all-dog-breeds/australian-shepherd.html"
+	yorkshire := srv.URL + "/all-dog-breeds/yorkshire-terrier.html"
+
+	// The pages are revalidated by every refresh.
+	path := filepath.Join(t.TempDir(), "breeds.snapshot")
+	opts := []dogfetch.Option{
+		dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}),
+		dogfetch.WithCacheTTL(-1),
+		dogfetch.WithSnapshotPath(path),
+	}
+
+	c := newTestClient(t, opts...)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatalf("(fail) Unable to load the fixture pages. (err: %v)", err)
+	}
+
+	bi, err := c.GetByName("Australian Shepherd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name   string
+		routes map[string]string
+		expect dogfetch.RefreshSummary
+	}{
+		{
+			name: "unchanged",
+			expect: dogfetch.RefreshSummary{
+				UnchangedPages: 1,
+			},
+		},
+		{
+			name: "added",
+			routes: map[string]string{
+				"/dog-breeds-a-z/": "dogbreedslist/dog-breeds-a-z.html",
+			},
+			expect: dogfetch.RefreshSummary{
+				FetchedPages:   []string{yorkshire},
+				UnchangedPages: 1,
+			},
+		},
+		{
+			name: "changed and removed",
+			routes: map[string]string{
+				"/dog-breeds-a-z/":                       "dogbreedslist/refresh/a-z-yorkshire.html",
+				"/all-dog-breeds/yorkshire-terrier.html": "dogbreedslist/refresh/yorkshire-terrier.html",
+			},
+			expect: dogfetch.RefreshSummary{
+				Removed:      []string{bi.Id},
+				FetchedPages: []string{yorkshire},
+				RemovedPages: []string{australian},
+			},
+		},
+	}
+
+	for i, T := range steps {
+		for path, fixture := range T.routes {
+			routes[path] = fixture
+		}
+
+		// The last refresh is made from the saved snapshot by a new client.
+		if i == len(steps)-1 {
+			c = newTestClient(t, opts...)
+		}
+
+		prev := c.GetAll()
+
+		sum, err := c.RefreshIncremental(context.Background())
+		if err != nil {
+			t.Fatalf("(fail) Unable to refresh the breeds. (step: %s, err: %v)", T.name, err)
+		}
+
+		yorkie, err := c.GetByName("Yorkshire Terrier")
+
+		switch T.name {
+		case "added":
+			if err != nil || !reflect.DeepEqual(sum.Added, []string{yorkie.Id}) {
+				t.Errorf("(fail) Expected the Yorkshire Terrier to be added. (output: %v, err: %v)", sum.Added, err)
+			}
+
+			if c.GetAll()[bi.Id] != prev[bi.Id] {
+				t.Errorf("(fail) Expected the breed of the unchanged page to be kept as it was.")
+			}
+
+			T.expect.Added = sum.Added
+		case "changed and removed":
+			if err != nil || !reflect.DeepEqual(sum.Changed, []string{yorkie.Id}) || yorkie.Lifespan[0] != 12 {
+				t.Errorf("(fail) Expected the Yorkshire Terrier to be changed. (output: %v, %v, err: %v)", sum.Changed, yorkie, err)
+			}
+
+			if _, err := c.GetByName("Australian Shepherd"); err == nil {
+				t.Errorf("(fail) Expected the breed of the removed page to be dropped.")
+			}
+
+			T.expect.Changed = sum.Changed
+		}
+
+		if !reflect.DeepEqual(*sum, T.expect) {
+			t.Errorf("(fail) Did not expect the summary of the refresh. (step: %s, output: %+v, expected: %+v)", T.name, *sum, T.expect)
+		}
+	}
+}
+
+func Test_Client_RefreshIncremental_rules(t *testing.T) {
+	srv := newFixtureServer(t, map[string]string{
+		"/dog-breeds-a-z/":                         "dogbreedslist/refresh/a-z-australian.html",
+		"/all-dog-breeds/australian-shepherd.html": "dogbreedslist/renamed-labels.html",
+	})
+
+	path := filepath.Join(t.TempDir(), "breeds.snapshot")
+	newClient := func(rules *dogfetch.Rules) *dogfetch.Client {
+		return newTestClient(t, dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL, Rules: rules}),
+			dogfetch.WithCacheTTL(-1), dogfetch.WithSnapshotPath(path))
+	}
+
+	// The built-in rules do not know the renamed labels of the page.
+	c := newClient(nil)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatalf("(fail) Unable to load the fixture pages. (err: %v)", err)
+	}
+
+	if bi, err := c.GetByName("Australian Shepherd"); err != nil || len(bi.OtherNames) != 0 {
+		t.Fatalf("(fail) Did not expect the built-in rules to match the renamed labels. (output: %+v, err: %v)", bi, err)
+	}
+
+	// Once the rules are fixed, the unchanged page is extracted again.
+	rules, err := dogfetch.LoadRulesFile("testdata/rules/dogbreedslist-renamed.json")
+	if err != nil {
+		t.Fatalf("(fail) Unable to load the rules. (err: %v)", err)
+	}
+
+	c = newClient(rules)
+
+	sum, err := c.RefreshIncremental(context.Background())
+	if err != nil {
+		t.Fatalf("(fail) Unable to refresh the breeds. (err: %v)", err)
+	}
+
+	if sum.UnchangedPages != 0 || len(sum.FetchedPages) != 1 || len(sum.Changed) != 1 {
+		t.Errorf("(fail) Expected the page to be extracted again. (output: %+v)", *sum)
+	}
+
+	if bi, err := c.GetByName("Australian Shepherd"); err != nil || !reflect.DeepEqual(bi.OtherNames, []string{"Aussie", "Little Blue Dog"}) {
+		t.Errorf("(fail) Did not extract the page with the new rules. (output: %+v, err: %v)", bi, err)
+	}
+
+	// The page is unchanged for the refreshes made with the same rules.
+	if sum, err := c.RefreshIncremental(context.Background()); err != nil || sum.UnchangedPages != 1 {
+		t.Errorf("(fail) Expected the page to be kept. (output: %+v, err: %v)", sum, err)
+	}
+}
@@ -0,0 +1,337 @@
+package dogfetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	URL "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rommms07/dogfetch/internal/utils"
+)
+
+// RobotsAgent is the product token the client looks for in the User-agent lines of the
+// robots.txt files. The rules of the * group apply when no group names it.
+const RobotsAgent = utils.ProductToken
+
+// robotsMaxAge is how long the robots.txt of a host is kept in memory before it is fetched
+// again through the response cache.
+const robotsMaxAge = 24 * time.Hour
+
+// robotsRetryAge is how long the failure to fetch the robots.txt of a host is kept in memory,
+// so the host is not asked again for every page, yet a temporary failure does not last.
+const robotsRetryAge = 5 * time.Minute
+
+// robotsMaxSize is the size after which a robots.txt file is no longer read, as told by
+// RFC 9309.
+const robotsMaxSize = 500 << 10
+
+// robotsRules are the rules of a robots.txt file which apply to RobotsAgent.
+type robotsRules struct {
+	rules []robotsRule
+	delay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+var (
+	robotsAllowAll    = &robotsRules{}
+	robotsDisallowAll = &robotsRules{rules: []robotsRule{newRobotsRule(false, "/")}}
+)
+
+func newRobotsRule(allow bool, pattern string) robotsRule {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	for i, ch := range pattern {
+		switch {
+		case ch == '*':
+			expr.WriteString(".*")
+		case ch == '$' && i == len(pattern)-1:
+			expr.WriteString("$")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	return robotsRule{allow: allow, pattern: pattern, re: regexp.MustCompile(expr.String())}
+}
+
+// parseRobots parses a robots.txt file, keeping the rules of the groups naming agent, or else
+// of the groups naming *. The groups naming the same agent are merged together.
+func parseRobots(P []byte, agent string) *robotsRules {
+	type group struct {
+		agents []string
+		rules  []robotsRule
+		delay  time.Duration
+	}
+
+	var groups []*group
+	var cur *group
+	inRules := false
+
+	for _, line := range strings.Split(string(P), "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		key, val, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+
+		switch key {
+		case "user-agent":
+			if cur == nil || inRules {
+				cur = &group{}
+				groups = append(groups, cur)
+				inRules = false
+			}
+
+			// The product token is matched without its version, if any.
+			token, _, _ := strings.Cut(val, "/")
+			cur.agents = append(cur.agents, strings.ToLower(strings.TrimSpace(token)))
+
+		case "allow", "disallow":
+			if cur == nil {
+				continue
+			}
+
+			inRules = true
+			if len(val) != 0 {
+				cur.rules = append(cur.rules, newRobotsRule(key == "allow", val))
+			}
+
+		case "crawl-delay":
+			if cur == nil {
+				continue
+			}
+
+			inRules = true
+			if secs, err := strconv.ParseFloat(val, 64); err == nil && secs > 0 {
+				cur.delay = time.Duration(secs * float64(time.Second))
+			}
+		}
+	}
+
+	pick := func(agent string) *robotsRules {
+		var res *robotsRules
+		for _, g := range groups {
+			for _, a := range g.agents {
+				if a != agent {
+					continue
+				}
+
+				if res == nil {
+					res = &robotsRules{}
+				}
+
+				res.rules = append(res.rules, g.rules...)
+				if g.delay > res.delay {
+					res.delay = g.delay
+				}
+
+				break
+			}
+		}
+
+		return res
+	}
+
+	if res := pick(strings.ToLower(agent)); res != nil {
+		return res
+	}
+
+	if res := pick("*"); res != nil {
+		return res
+	}
+
+	return robotsAllowAll
+}
+
+// allowed tells whether the rules allow path, which includes the query of the url. The rule
+// of the longest matching pattern wins, an Allow rule winning over a Disallow rule of the same
+// length.
+func (r *robotsRules) allowed(path string) bool {
+	allow, best := true, -1
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			allow, best = rule.allow, n
+		}
+	}
+
+	return allow
+}
+
+// robotsCache keeps the robots.txt rules of every host requested by a client, along with the
+// time at which the next request may be made to the hosts asking for a Crawl-delay.
+type robotsCache struct {
+	cache *utils.Cache
+
+	mu    sync.Mutex
+	hosts map[string]*robotsHost
+}
+
+type robotsHost struct {
+	// fetchMu is held while the robots.txt of the host is fetched, so it is fetched once.
+	fetchMu sync.Mutex
+
+	rules   *robotsRules
+	err     error
+	expires time.Time
+	next    time.Time
+}
+
+func newRobotsCache(cache *utils.Cache) *robotsCache {
+	return &robotsCache{cache: cache, hosts: make(map[string]*robotsHost)}
+}
+
+func (rc *robotsCache) host(u *URL.URL) *robotsHost {
+	key := u.Scheme + "://" + u.Host
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	h := rc.hosts[key]
+	if h == nil {
+		h = &robotsHost{}
+		rc.hosts[key] = h
+	}
+
+	return h
+}
+
+// allowed tells whether the robots.txt of the host of u allows RobotsAgent to fetch it. A
+// *HostError is returned if the robots.txt could not be fetched at all.
+func (rc *robotsCache) allowed(ctx context.Context, u *URL.URL) (bool, error) {
+	if u.Path == "/robots.txt" {
+		return true, nil
+	}
+
+	rules, err := rc.rules(ctx, u)
+	if err != nil {
+		return false, err
+	}
+
+	path := u.EscapedPath()
+	if len(path) == 0 {
+		path = "/"
+	}
+
+	if len(u.RawQuery) != 0 {
+		path += "?" + u.RawQuery
+	}
+
+	return rules.allowed(path), nil
+}
+
+func (rc *robotsCache) rules(ctx context.Context, u *URL.URL) (*robotsRules, error) {
+	h := rc.host(u)
+
+	h.fetchMu.Lock()
+	defer h.fetchMu.Unlock()
+
+	rc.mu.Lock()
+	rules, err, expires := h.rules, h.err, h.expires
+	rc.mu.Unlock()
+
+	if (rules != nil || err != nil) && time.Now().Before(expires) {
+		return rules, err
+	}
+
+	rules, maxAge, err := rc.fetch(ctx, u)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	rc.mu.Lock()
+	h.rules, h.err, h.expires = rules, err, time.Now().Add(maxAge)
+	rc.mu.Unlock()
+
+	return rules, err
+}
+
+// fetch fetches the robots.txt file of the host of u through the response cache, and tells how
+// long its rules hold. As told by RFC 9309, a missing file allows everything, while a file
+// which the server fails to give disallows everything. A *HostError is returned for a host
+// which cannot be reached at all. The failures only hold for robotsRetryAge.
+func (rc *robotsCache) fetch(ctx context.Context, u *URL.URL) (*robotsRules, time.Duration, error) {
+	robotsUrl := u.Scheme + "://" + u.Host + "/robots.txt"
+
+	res, _, err := rc.cache.NewCacheResponse(ctx, robotsUrl)
+	if err != nil {
+		var status *utils.StatusError
+		switch {
+		case !errors.As(err, &status):
+			return nil, robotsRetryAge, &HostError{Host: u.Host, Err: err}
+		case status.StatusCode >= 400 && status.StatusCode < 500 && status.StatusCode != http.StatusTooManyRequests:
+			return robotsAllowAll, robotsMaxAge, nil
+		default:
+			return robotsDisallowAll, robotsRetryAge, nil
+		}
+	}
+
+	defer res.Body.Close()
+
+	P, err := io.ReadAll(io.LimitReader(res.Body, robotsMaxSize))
+	if err != nil {
+		return nil, robotsRetryAge, &Error{Op: "read cache", URL: robotsUrl, Kind: ErrCacheCorrupt, Err: err}
+	}
+
+	return parseRobots(P, RobotsAgent), robotsMaxAge, nil
+}
+
+// wait waits for the Crawl-delay asked by the robots.txt of the host of u since the previous
+// request made to the host. Nothing is waited for until the robots.txt is fetched.
+func (rc *robotsCache) wait(ctx context.Context, u *URL.URL) error {
+	if u.Path == "/robots.txt" {
+		return nil
+	}
+
+	h := rc.host(u)
+	now := time.Now()
+
+	rc.mu.Lock()
+	if h.rules == nil || h.rules.delay <= 0 {
+		rc.mu.Unlock()
+		return nil
+	}
+
+	at := h.next
+	if at.Before(now) {
+		at = now
+	}
+
+	h.next = at.Add(h.rules.delay)
+	rc.mu.Unlock()
+
+	if !at.After(now) {
+		return nil
+	}
+
+	t := time.NewTimer(at.Sub(now))
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
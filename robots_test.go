@@ -0,0 +1,169 @@
+package dogfetch_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rommms07/dogfetch"
+)
+
+func Test_RobotsAllowed(t *testing.T) {
+	robots := []byte(`
+User-agent: *
+Disallow: /
+
+# The groups of the same agent are merged.
+User-agent: other
+User-agent: dogfetch
+Disallow: /private/
+Allow: /private/public
+Disallow: /*.php$
+
+User-agent: dogfetch
+Disallow: /search?q=
+Crawl-delay: 1.5
+`)
+
+	tests := []struct {
+		agent, path string
+		expected    bool
+	}{
+		{"dogfetch", "/", true},
+		{"dogfetch", "/private/", false},
+		{"dogfetch", "/private/page.html", false},
+		{"dogfetch", "/private/public/page.html", true},
+		{"dogfetch", "/index.php", false},
+		{"dogfetch", "/index.php?page=2", true},
+		{"dogfetch", "/search?q=collie", false},
+		{"Dogfetch", "/private/page.html", false},
+		{"other", "/private/page.html", false},
+		{"other", "/search?q=collie", true},
+		{"crawler", "/", false},
+	}
+
+	for _, test := range tests {
+		if allowed := dogfetch.RobotsAllowed(robots, test.agent, test.path); allowed != test.expected {
+			t.Errorf("(fail) Expected %v for %s of %s. (output: %v)", test.expected, test.path, test.agent, allowed)
+		}
+	}
+
+	if delay := dogfetch.RobotsDelay(robots, "dogfetch"); delay != 1500*time.Millisecond {
+		t.Errorf("(fail) Did not expect the crawl delay. (output: %v)", delay)
+	}
+
+	if !dogfetch.RobotsAllowed(nil, "dogfetch", "/private/") {
+		t.Errorf("(fail) Expected an empty robots.txt to allow everything.")
+	}
+}
+
+func Test_Client_Robots(t *testing.T) {
+	routes := map[string]string{"/robots.txt": "robots/dogbreedslist.txt"}
+	for path, fixture := range dogBreedsListRoutes {
+		routes[path] = fixture
+	}
+
+	srv := newFixtureServer(t, routes)
+	store := dogfetch.NewMemoryStore()
+
+	c := dogfetch.New(dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}), dogfetch.WithCacheStore(store))
+	breeds, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("(fail) Did not expect the blocked pages to fail the crawl. (err: %v)", err)
+	}
+
+	if bi := breeds.GetByName("Yorkshire Terrier"); bi != nil {
+		t.Errorf("(fail) Did not expect the blocked breed to be crawled. (output: %+v)", bi)
+	}
+
+	bi := breeds.GetByName("Australian Shepherd")
+	if bi == nil {
+		t.Fatalf("(fail) Unable to get the allowed breed.")
+	}
+
+	// A blocked reference is kept by its url, like an unavailable one.
+	ref := srv.URL + "/refs/australian-shepherd.html"
+	if data := bi.Refs[ref]; data != ref {
+		t.Errorf("(fail) Expected the blocked reference to be kept by its url. (output: %v)", data)
+	}
+
+	expected := []string{srv.URL + "/all-dog-breeds/yorkshire-terrier.html", ref}
+	if r := c.LastCrawl(); r == nil || r.Pages != 1 || !reflect.DeepEqual(r.Blocked, expected) {
+		t.Errorf("(fail) Did not expect the crawl report. (output: %+v)", r)
+	}
+
+	// The blocked pages are not even fetched.
+	for _, pageUrl := range expected {
+		if _, err := store.Get(dogfetch.CacheKey(pageUrl)); !errors.Is(err, dogfetch.ErrCacheMiss) {
+			t.Errorf("(fail) Did not expect the blocked page to be fetched: %s (err: %v)", pageUrl, err)
+		}
+	}
+}
+
+func Test_Client_Robots_failures(t *testing.T) {
+	var robotsStatus int32
+
+	fixtures := newFixtureServer(t, dogBreedsListRoutes)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(int(atomic.LoadInt32(&robotsStatus)))
+			return
+		}
+
+		fixtures.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	newClient := func(baseUrl string) *dogfetch.Client {
+		return dogfetch.New(dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: baseUrl}),
+			dogfetch.WithCacheStore(dogfetch.NewMemoryStore()), dogfetch.WithRetryPolicy(dogfetch.RetryPolicy{Attempts: 1}))
+	}
+
+	// A server failing to give its robots.txt disallows everything, but only for a while.
+	atomic.StoreInt32(&robotsStatus, http.StatusServiceUnavailable)
+
+	c := newClient(srv.URL)
+	if breeds, _ := c.Crawl(context.Background()); len(breeds) != 0 {
+		t.Errorf("(fail) Expected the host to be disallowed. (output: %d breeds)", len(breeds))
+	}
+
+	if expiry := c.RobotsExpiry(srv.URL); expiry <= 0 || expiry > 10*time.Minute {
+		t.Errorf("(fail) Expected the failure to be kept for a few minutes. (output: %v)", expiry)
+	}
+
+	// A missing robots.txt allows everything, for as long as a fetched one.
+	atomic.StoreInt32(&robotsStatus, http.StatusNotFound)
+
+	c = newClient(srv.URL)
+	if breeds, err := c.Crawl(context.Background()); err != nil || len(breeds) != 2 {
+		t.Errorf("(fail) Expected the host to be allowed. (output: %d breeds, err: %v)", len(breeds), err)
+	}
+
+	if expiry := c.RobotsExpiry(srv.URL); expiry < time.Hour {
+		t.Errorf("(fail) Expected the rules to be kept for long. (output: %v)", expiry)
+	}
+
+	// A host which cannot be reached is reported once, as unavailable.
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	c = newClient(down.URL)
+	_, err := c.Crawl(context.Background())
+
+	var crawlErr *dogfetch.CrawlError
+	var hostErr *dogfetch.HostError
+	if !errors.As(err, &crawlErr) || len(crawlErr.Errors) != 1 || !errors.As(err, &hostErr) || !errors.Is(err, dogfetch.ErrSourceUnavailable) {
+		t.Fatalf("(fail) Expected the host to be reported as unavailable. (output: %v)", err)
+	}
+
+	host := strings.TrimPrefix(down.URL, "http://")
+	if r := c.LastCrawl(); hostErr.Host != host || r == nil || !reflect.DeepEqual(r.Unavailable, []string{host}) {
+		t.Errorf("(fail) Expected the unavailable host in the crawl report. (output: %+v)", r)
+	}
+}
@@ -0,0 +1,437 @@
+package dogfetch
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rommms07/dogfetch/internal/utils"
+	"golang.org/x/net/html"
+)
+
+// RulesVersion is the version of the rules format understood by this package.
+const RulesVersion = 1
+
+//go:embed rules/*.json
+var builtinRules embed.FS
+
+// Rules are the extraction rules of a source, usually loaded from a JSON file such as
+//
+//	{
+//	  "version": 1,
+//	  "revision": "2022-08-14",
+//	  "root": "div.content",
+//	  "fields": {
+//	    "name": {"selector": "h1", "post": ["first"]},
+//	    "otherNames": {"selector": "row(Other names)", "split": ",", "post": ["trim", "unique"]}
+//	  }
+//	}
+//
+// Fields are keyed by the json name of the BreedInfo field they fill. The rules of the built-in
+// sources are embedded in the package, and can be replaced at runtime by the rules returned by
+// LoadRules or LoadRulesFile.
+type Rules struct {
+	Version int `json:"version"`
+
+	// Revision tells the markup the rules were written against apart. It is recorded in the
+	// snapshots, along with a sum of the rules, to tell the breeds extracted with other rules.
+	Revision string `json:"revision,omitempty"`
+
+	// Root selects the element every field is selected from. The whole page is used when it is
+	// empty.
+	Root string `json:"root,omitempty"`
+
+	Fields map[string]*Rule `json:"fields"`
+
+	root selector
+}
+
+// Rule tells how a single field is extracted from a page:
+//
+//  1. Selector selects the elements holding the field. (see the selector type)
+//  2. Each element gives its items, the elements under it selected by Items, or the element
+//     itself when Items is empty or selects nothing.
+//  3. Each item gives the value of its Attr attribute, or its text.
+//  4. Values are replaced by the matches of Regex, or of its first group when it has one.
+//  5. Values are split around Split.
+//  6. The Post chain is applied to the values, then only the first Limit values are kept.
+//
+// For the map fields (breedChars) the rule is applied to every element on its own, and the
+// first value is stored under the label of the table row holding the element.
+type Rule struct {
+	Selector string   `json:"selector"`
+	Items    string   `json:"items,omitempty"`
+	Attr     string   `json:"attr,omitempty"`
+	Regex    string   `json:"regex,omitempty"`
+	Split    string   `json:"split,omitempty"`
+	Post     []string `json:"post,omitempty"`
+	Limit    int      `json:"limit,omitempty"`
+
+	sel   selector
+	items selector
+	regex *regexp.Regexp
+}
+
+// postProcs are the steps a Post chain is made of. They are given the base url of the source
+// the page belongs to.
+var postProcs = map[string]func(base string, vals []string) []string{
+	// trim removes the surrounding spaces of the values and drops the empty ones.
+	"trim": func(_ string, vals []string) (res []string) {
+		for _, v := range cleanStrings(vals) {
+			if len(v) != 0 {
+				res = append(res, v)
+			}
+		}
+
+		return
+	},
+
+	// conjunctions splits the values joined by "and" or "&", and removes the remarks in
+	// parentheses.
+	"conjunctions": func(_ string, vals []string) []string {
+		return cleanResults(vals)
+	},
+
+	"unique": func(_ string, vals []string) []string {
+		return uniqueSet(vals)
+	},
+
+	"first": func(_ string, vals []string) []string {
+		if len(vals) > 1 {
+			return vals[:1]
+		}
+
+		return vals
+	},
+
+	// absolute resolves the paths against the base url of the source, and the protocol
+	// relative urls to https.
+	"absolute": func(base string, vals []string) []string {
+		res := make([]string, len(vals))
+
+		for i, v := range vals {
+			switch {
+			case strings.HasPrefix(v, "//"):
+				res[i] = "https:" + v
+			case strings.Contains(v, "//"):
+				res[i] = v
+			default:
+				res[i] = base + v
+			}
+		}
+
+		return res
+	},
+
+	// md5 replaces the values by their md5 sum, which is how the breeds are identified.
+	"md5": func(_ string, vals []string) []string {
+		res := make([]string, len(vals))
+
+		for i, v := range vals {
+			res[i] = utils.GetMd5Sum(v)
+		}
+
+		return res
+	},
+}
+
+// ruleFields are the BreedInfo fields which can be filled by a rule.
+var ruleFields = map[string]func(bi *BreedInfo, vals []string) error{
+	"name":         func(bi *BreedInfo, vals []string) error { bi.Name = firstOf(vals); return nil },
+	"type":         func(bi *BreedInfo, vals []string) error { bi.Type = firstOf(vals); return nil },
+	"history":      func(bi *BreedInfo, vals []string) error { bi.History = firstOf(vals); return nil },
+	"otherNames":   func(bi *BreedInfo, vals []string) error { bi.OtherNames = vals; return nil },
+	"origins":      func(bi *BreedInfo, vals []string) error { bi.Origin = vals; return nil },
+	"breedGroups":  func(bi *BreedInfo, vals []string) error { bi.BreedGroups = vals; return nil },
+	"size":         func(bi *BreedInfo, vals []string) error { bi.Size = vals; return nil },
+	"temperaments": func(bi *BreedInfo, vals []string) error { bi.Temperaments = vals; return nil },
+	"colors":       func(bi *BreedInfo, vals []string) error { bi.Colors = vals; return nil },
+	"images":       func(bi *BreedInfo, vals []string) error { bi.Images = vals; return nil },
+	"breedRecs":    func(bi *BreedInfo, vals []string) error { bi.BreedRecs = vals; return nil },
+
+	"lifeSpan": func(bi *BreedInfo, vals []string) (err error) {
+		bi.Lifespan, err = parseBounds(vals)
+		return
+	},
+
+	"litterSize": func(bi *BreedInfo, vals []string) (err error) {
+		bi.LitterSize, err = parseBounds(vals)
+		return
+	},
+
+	// The references are only listed here, and fetched by the crawler once the page is
+	// extracted.
+	"refs": func(bi *BreedInfo, vals []string) error {
+		for _, v := range vals {
+			bi.Refs[v] = nil
+		}
+
+		return nil
+	},
+}
+
+// ruleMapFields are the BreedInfo fields which are filled with the labelled values of a rule.
+var ruleMapFields = map[string]func(bi *BreedInfo, key, val string) error{
+	"breedChars": func(bi *BreedInfo, key, val string) error {
+		score, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		bi.BreedChars[key] = score
+		return nil
+	},
+}
+
+// rulesSource is implemented by the sources extracting their breeds with Rules, which can be
+// replaced without dogfetch itself changing.
+type rulesSource interface {
+	rules() *Rules
+}
+
+// id identifies the rules by their revision along with the sum of their encoding, so rules
+// changed without their revision being bumped are still told apart.
+func (rs *Rules) id() string {
+	P, err := json.Marshal(rs)
+	if err != nil {
+		return rs.Revision
+	}
+
+	return rs.Revision + "/" + utils.BodySum(P)[:12]
+}
+
+// LoadRules reads and checks the extraction rules from r.
+func LoadRules(r io.Reader) (*Rules, error) {
+	rs := &Rules{}
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(rs); err != nil {
+		return nil, fmt.Errorf("cannot read the rules: %w", err)
+	}
+
+	if err := rs.compile(); err != nil {
+		return nil, err
+	}
+
+	return rs, nil
+}
+
+// LoadRulesFile reads and checks the extraction rules from the file at path.
+func LoadRulesFile(path string) (*Rules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	rs, err := LoadRules(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return rs, nil
+}
+
+// mustLoadBuiltinRules loads the embedded rules of a built-in source. They are checked by the
+// tests, so an error is a bug of the package.
+func mustLoadBuiltinRules(name string) *Rules {
+	P, err := builtinRules.ReadFile("rules/" + name + ".json")
+	if err != nil {
+		panic(err)
+	}
+
+	rs, err := LoadRules(bytes.NewReader(P))
+	if err != nil {
+		panic(fmt.Sprintf("rules/%s.json: %v", name, err))
+	}
+
+	return rs
+}
+
+func (rs *Rules) compile() (err error) {
+	if rs.Version != RulesVersion {
+		return fmt.Errorf("unsupported rules version %d (expected %d)", rs.Version, RulesVersion)
+	}
+
+	if len(rs.Root) != 0 {
+		if rs.root, err = parseSelector(rs.Root); err != nil {
+			return fmt.Errorf("root: %w", err)
+		}
+	}
+
+	if rs.Fields["name"] == nil {
+		return errors.New("no rule for the name field")
+	}
+
+	for field, rule := range rs.Fields {
+		if ruleFields[field] == nil && ruleMapFields[field] == nil {
+			return fmt.Errorf("unknown field %q", field)
+		}
+
+		if err := rule.compile(); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Rule) compile() (err error) {
+	if r.sel, err = parseSelector(r.Selector); err != nil {
+		return
+	}
+
+	if len(r.Items) != 0 {
+		if r.items, err = parseSelector(r.Items); err != nil {
+			return
+		}
+	}
+
+	if len(r.Regex) != 0 {
+		if r.regex, err = regexp.Compile(r.Regex); err != nil {
+			return
+		}
+	}
+
+	for _, name := range r.Post {
+		if postProcs[name] == nil {
+			return fmt.Errorf("unknown post-processing step %q", name)
+		}
+	}
+
+	return nil
+}
+
+// extract fills a breed from the page parsed into doc, base being the url of the source.
+func (rs *Rules) extract(base string, doc *html.Node) (*BreedInfo, error) {
+	bi := &BreedInfo{
+		BreedChars: make(map[string]int64),
+		Refs:       make(map[string]any),
+	}
+
+	root := doc
+	if rs.root != nil {
+		if nodes := rs.root.selectAll(doc); len(nodes) != 0 {
+			root = nodes[0]
+		} else {
+			return nil, fmt.Errorf("no %s on the page", rs.Root)
+		}
+	}
+
+	for field, rule := range rs.Fields {
+		nodes := rule.sel.selectAll(root)
+
+		if set := ruleMapFields[field]; set != nil {
+			for _, n := range nodes {
+				vals := rule.apply(base, []*html.Node{n})
+				if len(vals) == 0 {
+					continue
+				}
+
+				if err := set(bi, rowLabel(n), vals[0]); err != nil {
+					return nil, fmt.Errorf("%s: %w", field, err)
+				}
+			}
+
+			continue
+		}
+
+		if err := ruleFields[field](bi, rule.apply(base, nodes)); err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+	}
+
+	if len(bi.Name) == 0 {
+		return nil, errors.New("no breed name on the page")
+	}
+
+	return bi, nil
+}
+
+func (r *Rule) apply(base string, nodes []*html.Node) []string {
+	var vals []string
+
+	for _, n := range nodes {
+		items := []*html.Node{n}
+		if r.items != nil {
+			if found := r.items.selectAll(n); len(found) != 0 {
+				items = found
+			}
+		}
+
+		for _, item := range items {
+			val := nodeText(item)
+			if len(r.Attr) != 0 {
+				val = attr(item, r.Attr)
+			}
+
+			vals = append(vals, r.match(val)...)
+		}
+	}
+
+	if len(r.Split) != 0 {
+		var split []string
+		for _, val := range vals {
+			split = append(split, strings.Split(val, r.Split)...)
+		}
+
+		vals = split
+	}
+
+	for _, name := range r.Post {
+		vals = postProcs[name](base, vals)
+	}
+
+	if r.Limit > 0 && len(vals) > r.Limit {
+		vals = vals[:r.Limit]
+	}
+
+	return vals
+}
+
+func (r *Rule) match(val string) (vals []string) {
+	if r.regex == nil {
+		return []string{val}
+	}
+
+	for _, m := range r.regex.FindAllStringSubmatch(val, -1) {
+		if len(m) > 1 {
+			vals = append(vals, m[1])
+		} else {
+			vals = append(vals, m[0])
+		}
+	}
+
+	return
+}
+
+func firstOf(vals []string) string {
+	if len(vals) == 0 {
+		return ""
+	}
+
+	return vals[0]
+}
+
+func parseBounds(vals []string) (bounds []uint64, err error) {
+	for _, val := range vals {
+		b, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		bounds = append(bounds, b)
+	}
+
+	return
+}
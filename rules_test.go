@@ -0,0 +1,66 @@
+package dogfetch_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/rommms07/dogfetch"
+)
+
+func Test_LoadRulesFile(t *testing.T) {
+	rules, err := dogfetch.LoadRulesFile("testdata/rules/dogbreedslist-renamed.json")
+	if err != nil {
+		t.Fatalf("(fail) Unable to load the rules. (err: %v)", err)
+	}
+
+	P, err := ioutil.ReadFile("testdata/dogbreedslist/renamed-labels.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The built-in rules do not know the renamed labels of the page.
+	bi, err := (&dogfetch.DogBreedsList{}).Extract(&dogfetch.Page{Body: P})
+	if err != nil {
+		t.Fatalf("(fail) Unable to extract the breed. (err: %v)", err)
+	}
+
+	if len(bi.OtherNames) != 0 || len(bi.Lifespan) != 0 {
+		t.Errorf("(fail) Did not expect the built-in rules to match the renamed labels. (output: %v, %v)", bi.OtherNames, bi.Lifespan)
+	}
+
+	bi, err = (&dogfetch.DogBreedsList{Rules: rules}).Extract(&dogfetch.Page{Body: P})
+	if err != nil {
+		t.Fatalf("(fail) Unable to extract the breed. (err: %v)", err)
+	}
+
+	if bi.Name != "Australian Shepherd" {
+		t.Errorf("(fail) Did not matched the expected dog breed name. (%s != Australian Shepherd)", bi.Name)
+	}
+
+	if len(bi.OtherNames) != 2 || bi.OtherNames[0] != "Aussie" || bi.OtherNames[1] != "Little Blue Dog" {
+		t.Errorf("(fail) Did not extract the other names with the loaded rules. (output: %v)", bi.OtherNames)
+	}
+
+	if len(bi.Lifespan) != 2 || bi.Lifespan[0] != 12 || bi.Lifespan[1] != 15 {
+		t.Errorf("(fail) Did not extract the life span with the loaded rules. (output: %v)", bi.Lifespan)
+	}
+}
+
+func Test_LoadRules_invalid(t *testing.T) {
+	tests := map[string]string{
+		"version":  `{"version": 2, "fields": {"name": {"selector": "h1"}}}`,
+		"name":     `{"version": 1, "fields": {"type": {"selector": "h1"}}}`,
+		"field":    `{"version": 1, "fields": {"name": {"selector": "h1"}, "weight": {"selector": "h2"}}}`,
+		"selector": `{"version": 1, "fields": {"name": {"selector": "h1[class"}}}`,
+		"regex":    `{"version": 1, "fields": {"name": {"selector": "h1", "regex": "("}}}`,
+		"post":     `{"version": 1, "fields": {"name": {"selector": "h1", "post": ["shout"]}}}`,
+		"unknown":  `{"version": 1, "fields": {"name": {"selector": "h1", "xpath": "//h1"}}}`,
+	}
+
+	for name, input := range tests {
+		if _, err := dogfetch.LoadRules(strings.NewReader(input)); err == nil {
+			t.Errorf("(fail) Expected an error from the invalid rules. (input: %s)", name)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package dogfetch
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// selector is a small subset of the CSS selectors used by the extraction rules. It is a list
+// of alternatives separated by commas, each of them a sequence of steps such as
+//
+//	div.content h1
+//	table.table-02 row(*) p[class^=star-]
+//	h3:text(References) ~ ul a, div.like a
+//
+// A step matches a tag, classes, attributes with [key], [key=val] or [key^=val] and the text
+// of the element with :text(...). Steps separated by spaces select descendants, while "~"
+// selects the first matching element following the previous one in the document. The
+// row(Label) step selects the value cell of the table rows labelled Label, or of every
+// labelled row with row(*).
+type selector [][]*step
+
+type step struct {
+	following bool
+
+	tag     string
+	classes []string
+	attrs   []attrMatch
+	text    *string
+	row     *string
+}
+
+type attrMatch struct {
+	key, op, val string
+}
+
+func parseSelector(s string) (selector, error) {
+	var sel selector
+
+	for _, alt := range splitOutside(s, ',') {
+		var steps []*step
+		following := false
+
+		for _, tok := range splitOutside(alt, ' ') {
+			if tok == "~" {
+				following = true
+				continue
+			}
+
+			st, err := parseStep(tok)
+			if err != nil {
+				return nil, fmt.Errorf("selector %q: %w", s, err)
+			}
+
+			st.following = following
+			following = false
+			steps = append(steps, st)
+		}
+
+		if len(steps) == 0 || following {
+			return nil, fmt.Errorf("selector %q: empty alternative", s)
+		}
+
+		if steps[0].following {
+			return nil, fmt.Errorf("selector %q: cannot start with ~", s)
+		}
+
+		sel = append(sel, steps)
+	}
+
+	if len(sel) == 0 {
+		return nil, fmt.Errorf("selector %q: empty", s)
+	}
+
+	return sel, nil
+}
+
+func parseStep(tok string) (*step, error) {
+	st := &step{}
+
+	if strings.HasPrefix(tok, "row(") {
+		if !strings.HasSuffix(tok, ")") {
+			return nil, fmt.Errorf("unterminated %q", tok)
+		}
+
+		label := tok[len("row(") : len(tok)-1]
+		st.row = &label
+		return st, nil
+	}
+
+	i := strings.IndexAny(tok, ".[:")
+	if i < 0 {
+		i = len(tok)
+	}
+
+	st.tag = tok[:i]
+	tok = tok[i:]
+
+	for len(tok) != 0 {
+		switch {
+		case tok[0] == '.':
+			end := strings.IndexAny(tok[1:], ".[:")
+			if end < 0 {
+				end = len(tok) - 1
+			}
+
+			st.classes = append(st.classes, tok[1:end+1])
+			tok = tok[end+1:]
+		case tok[0] == '[':
+			end := strings.IndexByte(tok, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated %q", tok)
+			}
+
+			m := attrMatch{key: tok[1:end]}
+			if j := strings.Index(m.key, "^="); j >= 0 {
+				m = attrMatch{key: m.key[:j], op: "^=", val: m.key[j+2:]}
+			} else if j := strings.IndexByte(m.key, '='); j >= 0 {
+				m = attrMatch{key: m.key[:j], op: "=", val: m.key[j+1:]}
+			}
+
+			st.attrs = append(st.attrs, m)
+			tok = tok[end+1:]
+		case strings.HasPrefix(tok, ":text("):
+			end := strings.IndexByte(tok, ')')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated %q", tok)
+			}
+
+			text := tok[len(":text("):end]
+			st.text = &text
+			tok = tok[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", tok)
+		}
+	}
+
+	return st, nil
+}
+
+// splitOutside splits s around sep, ignoring the separators found within parentheses or
+// brackets, and drops the empty parts.
+func splitOutside(s string, sep byte) (parts []string) {
+	depth, start := 0, 0
+
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			switch s[i] {
+			case '(', '[':
+				depth++
+			case ')', ']':
+				depth--
+			}
+
+			if s[i] != sep || depth != 0 {
+				continue
+			}
+		}
+
+		if part := strings.TrimSpace(s[start:i]); len(part) != 0 {
+			parts = append(parts, part)
+		}
+
+		start = i + 1
+	}
+
+	return
+}
+
+func (st *step) match(n *html.Node) bool {
+	if len(st.tag) != 0 && st.tag != "*" && n.Data != st.tag {
+		return false
+	}
+
+	for _, class := range st.classes {
+		if !hasClass("", class)(n) {
+			return false
+		}
+	}
+
+	for _, m := range st.attrs {
+		val, exists := "", false
+		for _, a := range n.Attr {
+			if a.Key == m.key {
+				val, exists = a.Val, true
+				break
+			}
+		}
+
+		switch {
+		case !exists:
+			return false
+		case m.op == "=" && val != m.val:
+			return false
+		case m.op == "^=" && !strings.HasPrefix(val, m.val):
+			return false
+		}
+	}
+
+	return st.text == nil || nodeText(n) == *st.text
+}
+
+// selectAll returns the elements under root matched by the selector, in the order of its
+// alternatives and without duplicates.
+func (sel selector) selectAll(root *html.Node) []*html.Node {
+	var nodes []*html.Node
+	seen := make(map[*html.Node]bool)
+
+	for _, steps := range sel {
+		current := []*html.Node{root}
+
+		for _, st := range steps {
+			var matched []*html.Node
+
+			for _, n := range current {
+				switch {
+				case st.row != nil:
+					matched = append(matched, rowCells(n, *st.row)...)
+				case st.following:
+					if found := next(n, st.match); found != nil {
+						matched = append(matched, found)
+					}
+				default:
+					matched = append(matched, findAll(n, st.match)...)
+				}
+			}
+
+			current = matched
+		}
+
+		for _, n := range current {
+			if !seen[n] {
+				seen[n] = true
+				nodes = append(nodes, n)
+			}
+		}
+	}
+
+	return nodes
+}
+
+// rowCells returns the second cell of the table rows under n whose first cell reads label,
+// or of every row with a label when label is "*".
+func rowCells(n *html.Node, label string) (cells []*html.Node) {
+	for _, tr := range findAll(n, isTag("tr")) {
+		tds := childCells(tr)
+		if len(tds) < 2 {
+			continue
+		}
+
+		if text := nodeText(tds[0]); len(text) != 0 && (label == "*" || text == label) {
+			cells = append(cells, tds[1])
+		}
+	}
+
+	return
+}
+
+// rowLabel returns the label of the table row enclosing n, or an empty string if n is not
+// within a labelled row.
+func rowLabel(n *html.Node) string {
+	for ; n != nil; n = n.Parent {
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			if tds := childCells(n); len(tds) != 0 {
+				return nodeText(tds[0])
+			}
+
+			return ""
+		}
+	}
+
+	return ""
+}
+
+func childCells(tr *html.Node) (cells []*html.Node) {
+	for c := tr.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "td" {
+			cells = append(cells, c)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,389 @@
+package dogfetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rommms07/dogfetch/internal/utils"
+)
+
+// SnapshotSchema is the version of the snapshot format written by this package. Snapshots of
+// an older schema are migrated as they are read, while the ones of a newer schema are refused.
+const SnapshotSchema = 3
+
+// snapshotFile is the name of the snapshot saved in the cache directory, unless
+// WithSnapshotPath says otherwise. It must not end with .json, which are the metadata files of
+// a FileStore.
+const snapshotFile = "breeds.snapshot"
+
+const opWriteSnapshot = "write snapshot"
+
+// Snapshot is a saved dataset along with what it was built with, such as
+//
+//	{
+//	  "header": {
+//	    "schema": 3,
+//	    "created": "2022-08-14T10:00:00Z",
+//	    "version": "0.2.0",
+//	    "sources": ["https://www.dogbreedslist.info"],
+//	    "extractor": "0.2.0 https://www.dogbreedslist.info=2022-08-14/5a6426ead09e"
+//	  },
+//	  "breeds": {"<id>": {"id": "<id>", "name": "Yorkshire Terrier", ...}},
+//	  "pages": {"https://www.dogbreedslist.info/all-dog-breeds/yorkshire-terrier.html": {...}}
+//	}
+type Snapshot struct {
+	Header SnapshotHeader `json:"header"`
+	Breeds BreedInfos     `json:"breeds"`
+
+	// Pages are the breed pages the breeds were merged from, keyed by their url. They tell the
+	// unchanged pages apart on an incremental refresh.
+	Pages map[string]*SnapshotPage `json:"pages,omitempty"`
+}
+
+type SnapshotHeader struct {
+	Schema  int       `json:"schema"`
+	Created time.Time `json:"created"`
+
+	// Version is the version of dogfetch which saved the snapshot.
+	Version string        `json:"version"`
+	Sources []BreedSource `json:"sources"`
+
+	// Extractor identifies how the breeds were extracted: the version of dogfetch, followed by
+	// the revision and the sum of the rules used by every source extracting its pages with
+	// Rules. It is empty when unknown.
+	Extractor string `json:"extractor,omitempty"`
+}
+
+// SnapshotPage is a breed page of a snapshot, along with the breed extracted from it alone.
+type SnapshotPage struct {
+	Source    BreedSource `json:"source"`
+	ETag      string      `json:"etag,omitempty"`
+	Sum       string      `json:"sum"`
+	FetchedAt time.Time   `json:"fetchedAt"`
+	Breed     *BreedInfo  `json:"breed"`
+}
+
+// unchanged tells whether the page fetched with etag and the body of sum is the same as p.
+func (p *SnapshotPage) unchanged(etag, sum string) bool {
+	if len(etag) != 0 && etag == p.ETag {
+		return true
+	}
+
+	return sum == p.Sum
+}
+
+// snapshotMigrations upgrade a snapshot from the schema of their index to the next one.
+var snapshotMigrations = []func(doc map[string]json.RawMessage) (map[string]json.RawMessage, error){
+	// Schema 0 is the bare map of breeds which used to be saved into /tmp/breeds.json, with
+	// nothing known about how it was built.
+	func(doc map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+		breeds, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]json.RawMessage{
+			"header": json.RawMessage(`{"schema":1}`),
+			"breeds": breeds,
+		}, nil
+	},
+
+	// Schema 1 has no pages, so every page is extracted again by the next incremental refresh.
+	func(doc map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+		return setSchema(doc, 2)
+	},
+
+	// Schema 2 only recorded the version of the rules format as its extractor, which tells
+	// nothing of the rules used, so the extractor is left unknown.
+	func(doc map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+		return setSchema(doc, 3, "extractor")
+	},
+}
+
+// setSchema sets the schema of the header of a snapshot, dropping the fields of the header
+// named by drop.
+func setSchema(doc map[string]json.RawMessage, schema int, drop ...string) (map[string]json.RawMessage, error) {
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(doc["header"], &header); err != nil {
+		return nil, err
+	}
+
+	for _, field := range drop {
+		delete(header, field)
+	}
+
+	header["schema"] = json.RawMessage(strconv.Itoa(schema))
+
+	P, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	doc["header"] = P
+	return doc, nil
+}
+
+// Snapshot returns a snapshot of the dataset of the client. Its extractor is the one the
+// dataset was extracted with, which is not the one of the client for a loaded snapshot.
+func (c *Client) Snapshot() *Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	s := c.newSnapshot(c.breeds, c.pages)
+	s.Header.Extractor = c.extracted
+	return s
+}
+
+// newSnapshot returns a snapshot of breeds, as crawled by the client from its sources.
+func (c *Client) newSnapshot(breeds BreedInfos, pages map[string]*SnapshotPage) *Snapshot {
+	sources := make([]BreedSource, 0, len(c.sources))
+	for _, src := range c.sources {
+		sources = append(sources, src.Name())
+	}
+
+	return &Snapshot{
+		Header: SnapshotHeader{
+			Schema:    SnapshotSchema,
+			Created:   time.Now().UTC(),
+			Version:   Version,
+			Sources:   sources,
+			Extractor: c.extractor(),
+		},
+		Breeds: breeds,
+		Pages:  pages,
+	}
+}
+
+// extractor identifies how the client extracts its breeds, as recorded in SnapshotHeader.
+func (c *Client) extractor() string {
+	parts := []string{Version}
+	for _, src := range c.sources {
+		if rs, ok := src.(rulesSource); ok {
+			parts = append(parts, string(src.Name())+"="+rs.rules().id())
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ReadSnapshot reads and checks a snapshot from r, migrating it to SnapshotSchema. An error of
+// kind ErrCacheCorrupt is returned if it cannot be read, or if any of its breeds is invalid.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	fail := func(err error) (*Snapshot, error) {
+		return nil, &Error{Op: "read snapshot", Kind: ErrCacheCorrupt, Err: err}
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return fail(err)
+	}
+
+	schema := 0
+	if header, exists := doc["header"]; exists {
+		var h struct {
+			Schema *int `json:"schema"`
+		}
+
+		if err := json.Unmarshal(header, &h); err != nil {
+			return fail(err)
+		}
+
+		if h.Schema == nil {
+			return fail(errors.New("no schema in the header"))
+		}
+
+		schema = *h.Schema
+	}
+
+	if schema < 0 || schema > SnapshotSchema {
+		return fail(fmt.Errorf("unsupported schema %d (expected at most %d)", schema, SnapshotSchema))
+	}
+
+	for ; schema < SnapshotSchema; schema++ {
+		var err error
+		if doc, err = snapshotMigrations[schema](doc); err != nil {
+			return fail(fmt.Errorf("migrating from schema %d: %w", schema, err))
+		}
+	}
+
+	P, err := json.Marshal(doc)
+	if err != nil {
+		return fail(err)
+	}
+
+	s := &Snapshot{}
+	dec := json.NewDecoder(bytes.NewReader(P))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(s); err != nil {
+		return fail(err)
+	}
+
+	if err := s.check(); err != nil {
+		return fail(err)
+	}
+
+	return s, nil
+}
+
+// check makes sure every breed of the snapshot can be looked up.
+func (s *Snapshot) check() error {
+	if s.Breeds == nil {
+		return errors.New("no breeds")
+	}
+
+	for id, bi := range s.Breeds {
+		switch {
+		case bi == nil:
+			return fmt.Errorf("breed %s: empty", id)
+		case bi.Id != id:
+			return fmt.Errorf("breed %s: mismatched id %q", id, bi.Id)
+		case len(bi.Name) == 0:
+			return fmt.Errorf("breed %s: no name", id)
+		}
+	}
+
+	for pageUrl, p := range s.Pages {
+		if p == nil || p.Breed == nil || len(p.Breed.Name) == 0 {
+			return fmt.Errorf("page %s: no breed", pageUrl)
+		}
+	}
+
+	return nil
+}
+
+// WriteTo writes the snapshot into w.
+func (s *Snapshot) WriteTo(w io.Writer) (int64, error) {
+	P, err := json.Marshal(s)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(append(P, '\n'))
+	return int64(n), err
+}
+
+// SaveSnapshot writes the dataset of the client into w.
+func (c *Client) SaveSnapshot(w io.Writer) error {
+	_, err := c.Snapshot().WriteTo(w)
+	return err
+}
+
+// LoadSnapshot replaces the dataset of the client with the snapshot read from r. The dataset
+// is left untouched if the snapshot cannot be read or is invalid.
+func (c *Client) LoadSnapshot(r io.Reader) error {
+	s, err := ReadSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	c.set(s)
+	return nil
+}
+
+// WithSnapshotPath sets the file the dataset is saved into once crawled, and loaded from by
+// Load. It defaults to breeds.snapshot in the cache directory when the client caches its pages
+// in files, and an empty path disables the snapshot.
+func WithSnapshotPath(path string) Option {
+	return func(c *Client) {
+		c.snapshotPath = &path
+	}
+}
+
+func (c *Client) snapshotFile() string {
+	switch {
+	case c.snapshotPath != nil:
+		return *c.snapshotPath
+	case c.cacheStore != nil:
+		return ""
+	case len(c.cacheDir) != 0:
+		return filepath.Join(c.cacheDir, snapshotFile)
+	}
+
+	dir, err := DefaultCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, snapshotFile)
+}
+
+// readSnapshotFile reads the snapshot saved at path. It returns nil and no error when there is
+// no such file.
+func readSnapshotFile(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, &Error{Op: "read snapshot", URL: path, Kind: ErrCacheCorrupt, Err: err}
+	}
+
+	defer f.Close()
+
+	s, err := ReadSnapshot(f)
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) {
+			e.URL = path
+		}
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// WriteFile saves the snapshot at path, creating its directory when needed. The file is either
+// replaced at once or left untouched.
+func (s *Snapshot) WriteFile(path string) error {
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		return &Error{Op: opWriteSnapshot, URL: path, Err: err}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return &Error{Op: opWriteSnapshot, URL: path, Err: err}
+	}
+
+	if err := utils.WriteFileAtomic(path, buf.Bytes()); err != nil {
+		return &Error{Op: opWriteSnapshot, URL: path, Err: err}
+	}
+
+	return nil
+}
+
+// savedSnapshot reads the snapshot saved by a previous crawl. It returns nil and no error when
+// there is none.
+func (c *Client) savedSnapshot() (*Snapshot, error) {
+	path := c.snapshotFile()
+	if len(path) == 0 {
+		return nil, nil
+	}
+
+	return readSnapshotFile(path)
+}
+
+// saveSnapshotFile saves s as the snapshot of the client, if it has one.
+func (c *Client) saveSnapshotFile(s *Snapshot) error {
+	path := c.snapshotFile()
+	if len(path) == 0 {
+		return nil
+	}
+
+	return s.WriteFile(path)
+}
+
+// isSnapshotWrite reports whether err is only about saving the snapshot of a dataset, which is
+// still usable.
+func isSnapshotWrite(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Op == opWriteSnapshot
+}
@@ -0,0 +1,151 @@
+package dogfetch_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rommms07/dogfetch"
+)
+
+func Test_Client_SaveSnapshot(t *testing.T) {
+	srv := newFixtureServer(t, dogBreedsListRoutes)
+	path := filepath.Join(t.TempDir(), "snapshots", "breeds.json")
+
+	c := newTestClient(t, dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL}), dogfetch.WithSnapshotPath(path))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatalf("(fail) Unable to load the fixture pages. (err: %v)", err)
+	}
+
+	// The crawled dataset is saved at the path of the snapshot, and loaded back by a new client
+	// without crawling anything.
+	offline := dogfetch.New(dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: "http://127.0.0.1:0"}),
+		dogfetch.WithCacheStore(dogfetch.NewMemoryStore()), dogfetch.WithSnapshotPath(path))
+
+	if err := offline.Load(context.Background()); err != nil {
+		t.Fatalf("(fail) Unable to load the saved snapshot. (err: %v)", err)
+	}
+
+	if len(offline.GetAll()) != len(c.GetAll()) || len(offline.GetAll()) == 0 {
+		t.Errorf("(fail) Did not load the saved breeds. (output: %d, expected: %d)", len(offline.GetAll()), len(c.GetAll()))
+	}
+
+	var buf bytes.Buffer
+	if err := c.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("(fail) Unable to save the snapshot. (err: %v)", err)
+	}
+
+	s, err := dogfetch.ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("(fail) Unable to read the saved snapshot. (err: %v)", err)
+	}
+
+	h := s.Header
+	extractor := dogfetch.Version + " " + srv.URL + "=2022-08-14/"
+	if h.Schema != dogfetch.SnapshotSchema || h.Version != dogfetch.Version || !strings.HasPrefix(h.Extractor, extractor) || h.Created.IsZero() {
+		t.Errorf("(fail) Did not expect the header of the snapshot. (output: %+v)", h)
+	}
+
+	// The breeds extracted with other rules are told apart.
+	rules, err := dogfetch.LoadRulesFile("testdata/rules/dogbreedslist-renamed.json")
+	if err != nil {
+		t.Fatalf("(fail) Unable to load the rules. (err: %v)", err)
+	}
+
+	renamed := newTestClient(t, dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: srv.URL, Rules: rules}))
+	if err := renamed.Refresh(context.Background()); err != nil {
+		t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+	}
+
+	if e := renamed.Snapshot().Header.Extractor; e == h.Extractor || !strings.HasPrefix(e, dogfetch.Version+" "+srv.URL+"=renamed-labels/") {
+		t.Errorf("(fail) Expected the extractor to change with the rules. (output: %s)", e)
+	}
+
+	if len(h.Sources) != 1 || h.Sources[0] != dogfetch.BreedSource(srv.URL) {
+		t.Errorf("(fail) Did not record the sources of the snapshot. (output: %v)", h.Sources)
+	}
+
+	if bi, err := offline.GetByName("Yorkshire Terrier"); err != nil || len(bi.OtherNames) == 0 {
+		t.Errorf("(fail) Did not read back the breeds of the snapshot. (output: %+v, err: %v)", bi, err)
+	}
+}
+
+func Test_LoadSnapshot_legacy(t *testing.T) {
+	const legacy = `{"3f2b":{"id":"3f2b","name":"Yorkshire Terrier","otherNames":["Yorkie"]}}`
+
+	c := dogfetch.New()
+	if err := c.LoadSnapshot(strings.NewReader(legacy)); err != nil {
+		t.Fatalf("(fail) Unable to load a snapshot of schema 0. (err: %v)", err)
+	}
+
+	if bi, err := c.GetById("3f2b"); err != nil || bi.Name != "Yorkshire Terrier" {
+		t.Errorf("(fail) Did not migrate the breeds of the snapshot. (output: %+v, err: %v)", bi, err)
+	}
+}
+
+func Test_ReadSnapshot_schema2(t *testing.T) {
+	// The extractor of schema 2 was the version of the rules format, which tells nothing of
+	// the rules used.
+	const schema2 = `{"header":{"schema":2,"version":"0.2.0","extractor":1},"breeds":{"3f2b":{"id":"3f2b","name":"Yorkshire Terrier"}}}`
+
+	s, err := dogfetch.ReadSnapshot(strings.NewReader(schema2))
+	if err != nil {
+		t.Fatalf("(fail) Unable to read a snapshot of schema 2. (err: %v)", err)
+	}
+
+	if s.Header.Schema != dogfetch.SnapshotSchema || len(s.Header.Extractor) != 0 || s.Header.Version != "0.2.0" {
+		t.Errorf("(fail) Did not migrate the header of the snapshot. (output: %+v)", s.Header)
+	}
+}
+
+func Test_LoadSnapshot_corrupt(t *testing.T) {
+	const valid = `{"header":{"schema":1},"breeds":{"3f2b":{"id":"3f2b","name":"Yorkshire Terrier"}}}`
+
+	c := dogfetch.New()
+	if err := c.LoadSnapshot(strings.NewReader(valid)); err != nil {
+		t.Fatalf("(fail) Unable to load the snapshot. (err: %v)", err)
+	}
+
+	for _, T := range []string{
+		`{"header":{"schema":1},"breeds":{"3f2b":{"id":"3f2b","name":"Yorkshire`,
+		fmt.Sprintf(`{"header":{"schema":%d},"breeds":{}}`, dogfetch.SnapshotSchema+1),
+		`{"header":{"version":"0.2.0"},"breeds":{}}`,
+		`{"header":{"schema":1}}`,
+		`{"header":{"schema":1},"breeds":{"3f2b":null}}`,
+		`{"header":{"schema":1},"breeds":{"3f2b":{"id":"a1c9","name":"Yorkshire Terrier"}}}`,
+		`{"header":{"schema":1},"breeds":{"3f2b":{"id":"3f2b","name":""}}}`,
+		`{"header":{"schema":1},"breeds":{"3f2b":{"id":"3f2b","name":"Yorkshire Terrier","lifeSpan":"long"}}}`,
+	} {
+		if err := c.LoadSnapshot(strings.NewReader(T)); !errors.Is(err, dogfetch.ErrCacheCorrupt) {
+			t.Errorf("(fail) Expected ErrCacheCorrupt from a corrupt snapshot. (input: %s, output: %v)", T, err)
+		}
+
+		if bi, err := c.GetById("3f2b"); err != nil || bi.Name != "Yorkshire Terrier" {
+			t.Errorf("(fail) Did not expect a corrupt snapshot to replace the dataset. (input: %s)", T)
+		}
+	}
+}
+
+func Test_Client_Load_corruptSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breeds.snapshot")
+	if err := os.WriteFile(path, []byte(`{"header":{"schema":1},"breeds":{`), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	// The client has no source, so nothing is crawled in place of the corrupt snapshot.
+	c := dogfetch.New(dogfetch.WithSources(), dogfetch.WithCacheStore(dogfetch.NewMemoryStore()), dogfetch.WithSnapshotPath(path))
+
+	var e *dogfetch.Error
+	if err := c.Load(context.Background()); !errors.Is(err, dogfetch.ErrCacheCorrupt) || !errors.As(err, &e) || e.URL != path {
+		t.Errorf("(fail) Expected ErrCacheCorrupt from the snapshot file. (output: %v)", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("(fail) Did not expect the corrupt snapshot to be removed. (err: %v)", err)
+	}
+}
@@ -0,0 +1,85 @@
+package dogfetch
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// DogBreedsList is the source of the breeds listed on https://www.dogbreedslist.info. Its
+// BaseURL defaults to Source1 when empty, and Rules to the extraction rules embedded in the
+// package, found in rules/dogbreedslist.json.
+type DogBreedsList struct {
+	BaseURL string
+	Rules   *Rules
+}
+
+func (s *DogBreedsList) base() string {
+	if len(s.BaseURL) != 0 {
+		return strings.TrimSuffix(s.BaseURL, "/")
+	}
+
+	return string(Source1)
+}
+
+func (s *DogBreedsList) Name() BreedSource {
+	return BreedSource(s.base())
+}
+
+// Discover returns the breed pages listed on the A-Z page of the source.
+func (s *DogBreedsList) Discover(ctx context.Context, f Fetcher) ([]string, error) {
+	patt := regexp.MustCompile(`<dd><a href="(?P<page>/all-dog-breeds/[^.]+.html)">.+?</a></dd>`)
+
+	pageList, err := f.Fetch(ctx, s.base()+"/dog-breeds-a-z/")
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []string
+	for _, indexes := range patt.FindAllSubmatchIndex(pageList.Body, -1) {
+		pagePath := string(patt.Expand([]byte{}, []byte(`$page`), pageList.Body, indexes))
+		pages = append(pages, s.base()+pagePath)
+	}
+
+	return pages, nil
+}
+
+func (s *DogBreedsList) Fetch(ctx context.Context, f Fetcher, pageUrl string) (*Page, error) {
+	return f.Fetch(ctx, pageUrl)
+}
+
+func (s *DogBreedsList) Extract(p *Page) (*BreedInfo, error) {
+	return s.digPage(p.Body)
+}
+
+// digPage parses the breed page once and selects every field of the breed with the
+// extraction rules of the source.
+func (s *DogBreedsList) digPage(P []byte) (*BreedInfo, error) {
+	doc, err := html.Parse(bytes.NewReader(P))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.rules().extract(s.base(), doc)
+}
+
+var (
+	dogBreedsListRulesOnce sync.Once
+	dogBreedsListRules     *Rules
+)
+
+func (s *DogBreedsList) rules() *Rules {
+	if s.Rules != nil {
+		return s.Rules
+	}
+
+	dogBreedsListRulesOnce.Do(func() {
+		dogBreedsListRules = mustLoadBuiltinRules("dogbreedslist")
+	})
+
+	return dogBreedsListRules
+}
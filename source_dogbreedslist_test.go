@@ -0,0 +1,118 @@
+package dogfetch_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/rommms07/dogfetch"
+	"github.com/rommms07/dogfetch/internal/utils"
+)
+
+func Test_DogBreedsList_Discover(t *testing.T) {
+	srv := newFixtureServer(t, dogBreedsListRoutes)
+	src := &dogfetch.DogBreedsList{BaseURL: srv.URL}
+
+	pages, err := src.Discover(context.Background(), dogfetch.NewCrawler(newTestClient(t, dogfetch.WithSources(src))))
+	if err != nil {
+		t.Fatalf("(fail) Unable to discover the breed pages. (err: %v)", err)
+	}
+
+	expected := []string{
+		srv.URL + "/all-dog-breeds/australian-shepherd.html",
+		srv.URL + "/all-dog-breeds/yorkshire-terrier.html",
+	}
+
+	if len(pages) != len(expected) {
+		t.Fatalf("(fail) Did not discover the expected pages. (output: %v)", pages)
+	}
+
+	for i, page := range pages {
+		if page != expected[i] {
+			t.Errorf("(fail) Did not discover the expected page. (%s != %s)", page, expected[i])
+		}
+	}
+}
+
+func Test_DogBreedsList_crawl(t *testing.T) {
+	srv := newFixtureServer(t, dogBreedsListRoutes)
+	src := &dogfetch.DogBreedsList{BaseURL: srv.URL}
+
+	dogs, err := newTestClient(t, dogfetch.WithSources(src)).Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+	}
+
+	if len(dogs) != 2 {
+		t.Fatalf("(fail) Expected number of dogs did not matched! (output: %d)", len(dogs))
+	}
+
+	id := utils.GetMd5Sum("/all-dog-breeds/australian-shepherd.html")
+	bi := dogs[id]
+
+	if bi == nil || bi.Id != id || bi.Name != "Australian Shepherd" {
+		t.Fatalf("(fail) Did not crawl the expected breed. (output: %+v)", bi)
+	}
+
+	sort.Strings(bi.OtherNames)
+	if len(bi.OtherNames) != 2 || bi.OtherNames[0] != "Aussie" || bi.OtherNames[1] != "Little Blue Dog" {
+		t.Errorf("(fail) Did not extract the other names. (output: %v)", bi.OtherNames)
+	}
+
+	if len(bi.Origin) != 1 || bi.Origin[0] != "United States" {
+		t.Errorf("(fail) Did not extract the origin. (output: %v)", bi.Origin)
+	}
+
+	if bi.Type != "Purebred" || len(bi.Size) != 2 {
+		t.Errorf("(fail) Did not extract the type and size. (output: %v, %v)", bi.Type, bi.Size)
+	}
+
+	if len(bi.Lifespan) != 2 || bi.Lifespan[0] != 12 || bi.Lifespan[1] != 15 {
+		t.Errorf("(fail) Did not extract the life span. (output: %v)", bi.Lifespan)
+	}
+
+	temperaments := []string{"Intelligent", "Good-natured", "Affectionate", "Protective"}
+	if len(bi.Temperaments) != len(temperaments) {
+		t.Errorf("(fail) Did not extract the temperaments. (output: %v)", bi.Temperaments)
+	}
+
+	for i := range bi.Temperaments {
+		if i < len(temperaments) && bi.Temperaments[i] != temperaments[i] {
+			t.Errorf("(fail) Did not extract the temperaments. (output: %v)", bi.Temperaments)
+			break
+		}
+	}
+
+	if len(bi.Colors) != 3 {
+		t.Errorf("(fail) Did not remove the duplicated colors. (output: %v)", bi.Colors)
+	}
+
+	if bi.History != "The Australian Shepherd was developed in the western United States to herd livestock." {
+		t.Errorf("(fail) Did not extract the history. (output: %v)", bi.History)
+	}
+
+	if bi.BreedChars["Trainability"] != 5 || bi.BreedChars["Shedding Level"] != 4 {
+		t.Errorf("(fail) Did not extract the breed characteristics. (output: %v)", bi.BreedChars)
+	}
+
+	if len(bi.Images) != 2 || bi.Images[0] != srv.URL+"/uploads/dog-pictures/australian-shepherd-1.jpg" {
+		t.Errorf("(fail) Did not extract the images. (output: %v)", bi.Images)
+	}
+
+	if len(bi.BreedRecs) != 1 || bi.BreedRecs[0] != utils.GetMd5Sum("/all-dog-breeds/yorkshire-terrier.html") {
+		t.Errorf("(fail) Did not extract the recommended breeds. (output: %v)", bi.BreedRecs)
+	}
+
+	ref, _ := bi.Refs[srv.URL+"/refs/australian-shepherd.html"].(map[string]any)
+	if ref == nil || ref["title"] != "Australian Shepherd Breed Club" {
+		t.Errorf("(fail) Did not fetch the data of the reference. (output: %v)", bi.Refs)
+	}
+
+	if bi.Refs[srv.URL+"/refs/standard.pdf"] != srv.URL+"/refs/standard.pdf" {
+		t.Errorf("(fail) Did not keep the pdf reference by its url. (output: %v)", bi.Refs)
+	}
+
+	if _, exists := bi.Refs[srv.URL+"/all-dog-breeds/australian-shepherd.html"]; !exists {
+		t.Errorf("(fail) Did not add the breed page into the references. (output: %v)", bi.Refs)
+	}
+}
@@ -0,0 +1,141 @@
+package dogfetch
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// InfoDogs is the source of the breeds listed on http://www.infodogs.co.uk, which fill the
+// gaps of the breeds of the other sources. It is not one of the DefaultSources. Its BaseURL
+// defaults to Source2 when empty.
+type InfoDogs struct {
+	BaseURL string
+}
+
+func (s *InfoDogs) base() string {
+	if len(s.BaseURL) != 0 {
+		return strings.TrimSuffix(s.BaseURL, "/")
+	}
+
+	return string(Source2)
+}
+
+func (s *InfoDogs) Name() BreedSource {
+	return BreedSource(s.base())
+}
+
+// Discover returns the breed pages listed by the search page of the source, following the
+// pagination of the results until the last page.
+func (s *InfoDogs) Discover(ctx context.Context, f Fetcher) ([]string, error) {
+	breedPatt := regexp.MustCompile(`(?s)<li class="breed-result">.*?<a href="(?P<page>/breeds/[^"]+/)">`)
+	nextPatt := regexp.MustCompile(`<a class="next" href="(?P<next>/search/[^"]*)"`)
+
+	var pages []string
+	seen := make(map[string]bool)
+
+	for next := "/search/"; len(next) != 0 && !seen[next]; {
+		seen[next] = true
+
+		results, err := f.Fetch(ctx, s.base()+next)
+		if err != nil {
+			return pages, err
+		}
+
+		for _, indexes := range breedPatt.FindAllSubmatchIndex(results.Body, -1) {
+			pagePath := string(breedPatt.Expand([]byte{}, []byte(`$page`), results.Body, indexes))
+			pages = append(pages, s.base()+pagePath)
+		}
+
+		indexes := nextPatt.FindSubmatchIndex(results.Body)
+		next = strings.ReplaceAll(string(nextPatt.Expand([]byte{}, []byte(`$next`), results.Body, indexes)), "&amp;", "&")
+	}
+
+	return pages, nil
+}
+
+func (s *InfoDogs) Fetch(ctx context.Context, f Fetcher, pageUrl string) (*Page, error) {
+	return f.Fetch(ctx, pageUrl)
+}
+
+func (s *InfoDogs) Extract(p *Page) (*BreedInfo, error) {
+	return s.digPage(p.Body)
+}
+
+func (s *InfoDogs) digPage(P []byte) (bi *BreedInfo, err error) {
+	bi = &BreedInfo{
+		BreedChars: make(map[string]int64),
+		Refs:       make(map[string]any),
+	}
+
+	namePatt := regexp.MustCompile(`<h1 class="breed-title">(?P<name>[^<]+)</h1>`)
+	imagesPatt := regexp.MustCompile(`(?s)<div class="breed-gallery">(?P<images>.+?)</div>`)
+	srcPatt := regexp.MustCompile(`<img.*?src="(?P<imageSrc>[^"]+)"`)
+	historyPatt := regexp.MustCompile(`(?s)<div class="breed-history">.*?<p>(?P<history>.+?)</p>`)
+	rangePatt := regexp.MustCompile(`(?P<start>\d+)\s*-\s*(?P<end>\d+)`)
+
+	indices := namePatt.FindSubmatchIndex(P)
+	bi.Name = strings.TrimSpace(string(namePatt.Expand([]byte{}, []byte(`$name`), P, indices)))
+
+	if len(bi.Name) == 0 {
+		return nil, errors.New("no breed name on the page")
+	}
+
+	bi.OtherNames = uniqueSet(cleanStrings(strings.Split(s.fact(P, "Also known as"), ",")))
+	bi.Origin = cleanResults(strings.Split(s.fact(P, "Origin"), ","))
+	bi.BreedGroups = cleanResults(strings.Split(s.fact(P, "Breed group"), ","))
+	bi.Size = cleanResults(strings.Split(s.fact(P, "Size"), " to "))
+	bi.Temperaments = cleanResults(strings.Split(s.fact(P, "Temperament"), ","))
+	bi.Colors = uniqueSet(cleanResults(strings.Split(s.fact(P, "Colours"), ",")))
+	bi.Type = s.fact(P, "Type")
+
+	for _, field := range []struct {
+		label  string
+		values *[]uint64
+	}{
+		{"Lifespan", &bi.Lifespan},
+		{"Litter size", &bi.LitterSize},
+	} {
+		fact := s.fact(P, field.label)
+
+		indices := rangePatt.FindStringSubmatchIndex(fact)
+		if indices == nil {
+			continue
+		}
+
+		for _, tmp := range []string{`$start`, `$end`} {
+			n, _ := strconv.ParseUint(string(rangePatt.ExpandString([]byte{}, tmp, fact, indices)), 10, 64)
+			*field.values = append(*field.values, n)
+		}
+	}
+
+	indices = imagesPatt.FindSubmatchIndex(P)
+	images := imagesPatt.Expand([]byte{}, []byte(`$images`), P, indices)
+
+	for _, indices := range srcPatt.FindAllSubmatchIndex(images, -1) {
+		src := string(srcPatt.Expand([]byte{}, []byte(`$imageSrc`), images, indices))
+		if !strings.Contains(src, "//") {
+			src = s.base() + src
+		}
+
+		bi.Images = append(bi.Images, src)
+	}
+
+	indices = historyPatt.FindSubmatchIndex(P)
+	bi.History = removeMisc(string(historyPatt.Expand([]byte{}, []byte(`$history`), P, indices)))
+
+	return
+}
+
+// fact returns the value of a row of the breed facts list, or an empty string if the page
+// has no such row.
+func (s *InfoDogs) fact(P []byte, label string) string {
+	patt := regexp.MustCompile(`(?s)<dl class="breed-facts">.*?<dt>` + regexp.QuoteMeta(label) + `</dt>\s*<dd>(?P<value>.*?)</dd>`)
+
+	indices := patt.FindSubmatchIndex(P)
+	value := string(patt.Expand([]byte{}, []byte(`$value`), P, indices))
+
+	return strings.TrimSpace(replaceConjunctions(removeMisc(value)))
+}
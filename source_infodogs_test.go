@@ -0,0 +1,135 @@
+package dogfetch_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/rommms07/dogfetch"
+	"github.com/rommms07/dogfetch/internal/utils"
+)
+
+func Test_InfoDogs_Discover(t *testing.T) {
+	srv := newFixtureServer(t, infoDogsRoutes)
+	src := &dogfetch.InfoDogs{BaseURL: srv.URL}
+
+	pages, err := src.Discover(context.Background(), dogfetch.NewCrawler(newTestClient(t, dogfetch.WithSources(src))))
+	if err != nil {
+		t.Fatalf("(fail) Unable to discover the breed pages. (err: %v)", err)
+	}
+
+	expected := []string{
+		srv.URL + "/breeds/border-collie/",
+		srv.URL + "/breeds/yorkshire-terrier/",
+		srv.URL + "/breeds/welsh-corgi-pembroke/",
+	}
+
+	if len(pages) != len(expected) {
+		t.Fatalf("(fail) Did not discover the expected pages. (output: %v)", pages)
+	}
+
+	for i, page := range pages {
+		if page != expected[i] {
+			t.Errorf("(fail) Did not discover the expected page. (%s != %s)", page, expected[i])
+		}
+	}
+}
+
+func Test_InfoDogs_Extract(t *testing.T) {
+	srv := newFixtureServer(t, infoDogsRoutes)
+	src := &dogfetch.InfoDogs{BaseURL: srv.URL}
+	cr := dogfetch.NewCrawler(newTestClient(t, dogfetch.WithSources(src)))
+
+	page, err := src.Fetch(context.Background(), cr, srv.URL+"/breeds/border-collie/")
+	if err != nil {
+		t.Fatalf("(fail) Unable to fetch the breed page. (err: %v)", err)
+	}
+
+	bi, err := src.Extract(page)
+	if err != nil {
+		t.Fatalf("(fail) Unable to extract the breed. (err: %v)", err)
+	}
+
+	if bi.Name != "Border Collie" {
+		t.Errorf("(fail) Did not matched the expected dog breed name. (%s != Border Collie)", bi.Name)
+	}
+
+	sort.Strings(bi.OtherNames)
+	if len(bi.OtherNames) != 2 || bi.OtherNames[0] != "Scottish Sheepdog" || bi.OtherNames[1] != "Working Collie" {
+		t.Errorf("(fail) Did not extract the other names. (output: %v)", bi.OtherNames)
+	}
+
+	if len(bi.Origin) != 2 || bi.Origin[0] != "Scotland" || bi.Origin[1] != "England" {
+		t.Errorf("(fail) Did not extract the origins. (output: %v)", bi.Origin)
+	}
+
+	if len(bi.Lifespan) != 2 || bi.Lifespan[0] != 12 || bi.Lifespan[1] != 15 {
+		t.Errorf("(fail) Did not extract the life span. (output: %v)", bi.Lifespan)
+	}
+
+	if len(bi.LitterSize) != 2 || bi.LitterSize[0] != 4 || bi.LitterSize[1] != 8 {
+		t.Errorf("(fail) Did not extract the litter size. (output: %v)", bi.LitterSize)
+	}
+
+	if len(bi.Colors) != 4 {
+		t.Errorf("(fail) Did not extract the colours. (output: %v)", bi.Colors)
+	}
+
+	if len(bi.Images) != 2 || bi.Images[0] != srv.URL+"/images/breeds/border-collie-1.jpg" {
+		t.Errorf("(fail) Did not extract the images. (output: %v)", bi.Images)
+	}
+
+	if bi.History != "The Border Collie was bred in the Anglo-Scottish border region for herding sheep." {
+		t.Errorf("(fail) Did not extract the history. (output: %v)", bi.History)
+	}
+
+	if _, err := src.Extract(&dogfetch.Page{URL: srv.URL, Body: []byte("<html></html>")}); err == nil {
+		t.Errorf("(fail) Expected an error from a page without a breed.")
+	}
+}
+
+func Test_InfoDogs_fillsGaps(t *testing.T) {
+	// The Yorkshire Terrier of the first source lacks its litter size.
+	routes := make(map[string]string)
+	for path, fixture := range dogBreedsListRoutes {
+		routes[path] = fixture
+	}
+
+	routes["/all-dog-breeds/yorkshire-terrier.html"] = "dogbreedslist/gaps/yorkshire-terrier.html"
+
+	dblSrv := newFixtureServer(t, routes)
+	idSrv := newFixtureServer(t, infoDogsRoutes)
+
+	c := newTestClient(t, dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: dblSrv.URL}, &dogfetch.InfoDogs{BaseURL: idSrv.URL}))
+
+	dogs, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+	}
+
+	// Yorkshire Terrier is listed on both sources, so it is only kept once.
+	if len(dogs) != 4 {
+		t.Errorf("(fail) Expected number of dogs did not matched! (output: %d)", len(dogs))
+	}
+
+	york := dogs[utils.GetMd5Sum("/all-dog-breeds/yorkshire-terrier.html")]
+	if york == nil {
+		t.Fatalf("(fail) Did not keep the breed of the first source.")
+	}
+
+	if len(york.Origin) != 2 || york.Origin[0] != "United Kingdom" || york.Origin[1] != "England" {
+		t.Errorf("(fail) Did not merge the origins of both sources. (output: %v)", york.Origin)
+	}
+
+	if len(york.LitterSize) != 2 || york.LitterSize[0] != 3 || york.LitterSize[1] != 5 {
+		t.Errorf("(fail) Did not fill the litter size from the second source. (output: %v)", york.LitterSize)
+	}
+
+	if _, exists := york.Refs[idSrv.URL+"/breeds/yorkshire-terrier/"]; !exists {
+		t.Errorf("(fail) Did not keep the page of the second source as a reference. (output: %v)", york.Refs)
+	}
+
+	if dogs[utils.GetMd5Sum("/breeds/border-collie/")] == nil {
+		t.Errorf("(fail) Did not add the breed only listed on the second source.")
+	}
+}
@@ -0,0 +1,130 @@
+package dogfetch
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// KennelClub is the source of the breed standards published on https://www.thekennelclub.org.uk.
+// Its breeds carry the Kennel Club classification in their KennelClub field, which is
+// attached to the matching breed of the other sources, the breeds matching none being
+// dropped. It is not one of the DefaultSources. Its BaseURL defaults to Source3 when empty.
+type KennelClub struct {
+	BaseURL string
+}
+
+func (s *KennelClub) base() string {
+	if len(s.BaseURL) != 0 {
+		return strings.TrimSuffix(s.BaseURL, "/")
+	}
+
+	return string(Source3)
+}
+
+func (s *KennelClub) Name() BreedSource {
+	return BreedSource(s.base())
+}
+
+// AttachOnly tells that the classification is only attached to the breeds of the other
+// sources.
+func (s *KennelClub) AttachOnly() bool {
+	return true
+}
+
+// Discover returns the breed pages found by the content search of the source, following the
+// pagination of the results until the last page.
+func (s *KennelClub) Discover(ctx context.Context, f Fetcher) ([]string, error) {
+	breedPatt := regexp.MustCompile(`<a class="m-breed-card__link" href="(?P<page>/search/breeds-a-to-z/breeds/[^"]+/)"`)
+	nextPatt := regexp.MustCompile(`<a[^>]*rel="next"[^>]*href="(?P<next>/search/content-search/[^"]*)"`)
+
+	var pages []string
+	seen := make(map[string]bool)
+
+	for next := "/search/content-search/?category=breeds"; len(next) != 0 && !seen[next]; {
+		seen[next] = true
+
+		results, err := f.Fetch(ctx, s.base()+next)
+		if err != nil {
+			return pages, err
+		}
+
+		for _, indexes := range breedPatt.FindAllSubmatchIndex(results.Body, -1) {
+			pagePath := string(breedPatt.Expand([]byte{}, []byte(`$page`), results.Body, indexes))
+			pages = append(pages, s.base()+pagePath)
+		}
+
+		indexes := nextPatt.FindSubmatchIndex(results.Body)
+		next = strings.ReplaceAll(string(nextPatt.Expand([]byte{}, []byte(`$next`), results.Body, indexes)), "&amp;", "&")
+	}
+
+	return pages, nil
+}
+
+func (s *KennelClub) Fetch(ctx context.Context, f Fetcher, pageUrl string) (*Page, error) {
+	return f.Fetch(ctx, pageUrl)
+}
+
+func (s *KennelClub) Extract(p *Page) (*BreedInfo, error) {
+	return s.digPage(p.URL, p.Body)
+}
+
+func (s *KennelClub) digPage(pageUrl string, P []byte) (bi *BreedInfo, err error) {
+	bi = &BreedInfo{
+		BreedChars: make(map[string]int64),
+		Refs:       make(map[string]any),
+	}
+
+	namePatt := regexp.MustCompile(`<h1 class="m-breed-header__title">(?P<name>[^<]+)</h1>`)
+	standardPatt := regexp.MustCompile(`(?s)<div class="m-breed-standard">.*?<ul>(?P<standard>.+?)</ul>`)
+	itemPatt := regexp.MustCompile(`(?s)<li>(?P<item>.+?)</li>`)
+	tagPatt := regexp.MustCompile(`<[^>]+>`)
+
+	indices := namePatt.FindSubmatchIndex(P)
+	bi.Name = strings.TrimSpace(string(namePatt.Expand([]byte{}, []byte(`$name`), P, indices)))
+
+	if len(bi.Name) == 0 {
+		return nil, errors.New("no breed name on the page")
+	}
+
+	kc := &KennelClubInfo{
+		URL:      pageUrl,
+		Group:    s.summary(P, "Breed group"),
+		Size:     s.summary(P, "Size"),
+		Exercise: s.summary(P, "Exercise"),
+		Grooming: s.summary(P, "Grooming"),
+	}
+
+	indices = standardPatt.FindSubmatchIndex(P)
+	standard := standardPatt.Expand([]byte{}, []byte(`$standard`), P, indices)
+
+	for _, indices := range itemPatt.FindAllSubmatchIndex(standard, -1) {
+		item := tagPatt.ReplaceAll(itemPatt.Expand([]byte{}, []byte(`$item`), standard, indices), nil)
+		item = []byte(strings.Join(strings.Fields(string(item)), " "))
+		kc.StandardHighlights = append(kc.StandardHighlights, cleanStrings([]string{string(item)})...)
+	}
+
+	if len(kc.Group) != 0 {
+		bi.BreedGroups = []string{kc.Group}
+	}
+
+	if len(kc.Size) != 0 {
+		bi.Size = []string{kc.Size}
+	}
+
+	bi.KennelClub = kc
+	return
+}
+
+// summary returns the value of an item of the breed summary, or an empty string if the page
+// has no such item.
+func (s *KennelClub) summary(P []byte, label string) string {
+	patt := regexp.MustCompile(`(?s)<span class="m-breed-summary__key-label">\s*` + regexp.QuoteMeta(label) +
+		`\s*</span>\s*<span class="m-breed-summary__key-value">(?P<value>.*?)</span>`)
+
+	indices := patt.FindSubmatchIndex(P)
+	value := string(patt.Expand([]byte{}, []byte(`$value`), P, indices))
+
+	return strings.TrimSpace(replaceConjunctions(removeMisc(value)))
+}
@@ -0,0 +1,99 @@
+package dogfetch_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/rommms07/dogfetch"
+	"github.com/rommms07/dogfetch/internal/utils"
+)
+
+func Test_KennelClub_Extract(t *testing.T) {
+	srv := newFixtureServer(t, kennelClubRoutes)
+	src := &dogfetch.KennelClub{BaseURL: srv.URL}
+	cr := dogfetch.NewCrawler(newTestClient(t, dogfetch.WithSources(src)))
+
+	pages, err := src.Discover(context.Background(), cr)
+	if err != nil || len(pages) != 2 {
+		t.Fatalf("(fail) Did not discover the expected pages. (output: %v, err: %v)", pages, err)
+	}
+
+	page, err := src.Fetch(context.Background(), cr, pages[0])
+	if err != nil {
+		t.Fatalf("(fail) Unable to fetch the breed page. (err: %v)", err)
+	}
+
+	bi, err := src.Extract(page)
+	if err != nil {
+		t.Fatalf("(fail) Unable to extract the breed. (err: %v)", err)
+	}
+
+	kc := bi.KennelClub
+	if bi.Name != "Border Collie" || kc == nil {
+		t.Fatalf("(fail) Did not extract the expected breed. (output: %+v)", bi)
+	}
+
+	if kc.URL != pages[0] || kc.Group != "Pastoral" || kc.Size != "Medium" {
+		t.Errorf("(fail) Did not extract the classification. (output: %+v)", kc)
+	}
+
+	if kc.Exercise != "More than 2 hours per day" || kc.Grooming != "More than once a week" {
+		t.Errorf("(fail) Did not extract the exercise and grooming categories. (output: %+v)", kc)
+	}
+
+	if len(kc.StandardHighlights) != 3 || kc.StandardHighlights[1] != "Temperament: Keen, alert, responsive and intelligent." {
+		t.Errorf("(fail) Did not extract the breed standard highlights. (output: %q)", kc.StandardHighlights)
+	}
+}
+
+func Test_KennelClub_attachesToBreed(t *testing.T) {
+	idSrv := newFixtureServer(t, infoDogsRoutes)
+	kcSrv := newFixtureServer(t, kennelClubRoutes)
+
+	c := newTestClient(t, dogfetch.WithSources(&dogfetch.InfoDogs{BaseURL: idSrv.URL}, &dogfetch.KennelClub{BaseURL: kcSrv.URL}))
+
+	dogs, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+	}
+
+	if len(dogs) != 3 {
+		t.Errorf("(fail) Expected number of dogs did not matched! (output: %d)", len(dogs))
+	}
+
+	york := dogs[utils.GetMd5Sum("/breeds/yorkshire-terrier/")]
+	if york == nil || york.KennelClub == nil {
+		t.Fatalf("(fail) Did not attach the Kennel Club classification to the breed. (output: %+v)", york)
+	}
+
+	if york.KennelClub.Group != "Toy" || york.KennelClub.Grooming != "Every day" {
+		t.Errorf("(fail) Did not attach the expected classification. (output: %+v)", york.KennelClub)
+	}
+
+	if corgi := dogs[utils.GetMd5Sum("/breeds/welsh-corgi-pembroke/")]; corgi == nil || corgi.KennelClub != nil {
+		t.Errorf("(fail) Did not expect a classification for a breed missing on the Kennel Club. (output: %+v)", corgi)
+	}
+
+	// The Border Collie is not on the fixture pages of dogbreedslist.info, so its
+	// classification has no breed to be attached to.
+	dblSrv := newFixtureServer(t, dogBreedsListRoutes)
+	c = newTestClient(t, dogfetch.WithSources(&dogfetch.DogBreedsList{BaseURL: dblSrv.URL}, &dogfetch.KennelClub{BaseURL: kcSrv.URL}))
+
+	if dogs, err = c.Crawl(context.Background()); err != nil {
+		t.Fatalf("(fail) Unable to crawl the fixture pages. (err: %v)", err)
+	}
+
+	if len(dogs) != 2 || dogs.GetByName("Border Collie") != nil {
+		t.Errorf("(fail) Did not expect an unmatched Kennel Club record to add a breed. (output: %d dogs)", len(dogs))
+	}
+
+	if york := dogs.GetByName("Yorkshire Terrier"); york == nil || york.KennelClub == nil {
+		t.Errorf("(fail) Did not attach the Kennel Club classification to the breed. (output: %+v)", york)
+	}
+
+	unmatched := []string{kcSrv.URL + "/search/breeds-a-to-z/breeds/pastoral/border-collie/"}
+	if r := c.LastCrawl(); r == nil || !reflect.DeepEqual(r.Unmatched, unmatched) {
+		t.Errorf("(fail) Expected the unmatched page in the crawl report. (output: %+v)", r)
+	}
+}
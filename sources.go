@@ -1,5 +1,10 @@
 package dogfetch
 
+import (
+	"context"
+	"time"
+)
+
 type BreedSource string
 
 const (
@@ -7,3 +12,57 @@ const (
 	Source2             = "http://www.infodogs.co.uk"
 	Source3             = "https://www.thekennelclub.org.uk"
 )
+
+// Page is a page fetched from a source.
+type Page struct {
+	URL       string
+	Body      []byte
+	FetchedAt time.Time
+
+	// ETag is the entity tag of the page, when its source sent one.
+	ETag string
+}
+
+// Fetcher fetches pages through the response cache of the client.
+type Fetcher interface {
+	Fetch(ctx context.Context, pageUrl string) (*Page, error)
+}
+
+// Source is a website the crawler collects breeds from. The crawler discovers the breed pages
+// of every source, then fetches and extracts each of them.
+type Source interface {
+	// Name identifies the source, usually by the base url of the website.
+	Name() BreedSource
+
+	// Discover returns the urls of the breed pages of the source.
+	Discover(ctx context.Context, f Fetcher) ([]string, error)
+
+	// Fetch fetches a single breed page.
+	Fetch(ctx context.Context, f Fetcher, pageUrl string) (*Page, error)
+
+	// Extract extracts the breed of a page. The references found on the page are added into
+	// Refs with a nil value, the crawler then fetches their data.
+	Extract(p *Page) (*BreedInfo, error)
+}
+
+// Attacher is implemented by the sources whose breeds only complete the matching breeds of the
+// other sources, such as KennelClub. Their breeds which match no breed of another source are
+// dropped, and reported in CrawlReport.Unmatched.
+type Attacher interface {
+	AttachOnly() bool
+}
+
+func attachOnly(src Source) bool {
+	a, ok := src.(Attacher)
+	return ok && a.AttachOnly()
+}
+
+// DefaultSources returns the sources crawled by a client created without WithSources. InfoDogs
+// and the classification of The Kennel Club are not crawled by default, they are added with
+//
+//	dogfetch.New(dogfetch.WithSources(append(dogfetch.DefaultSources(), &dogfetch.KennelClub{})...))
+//
+// or with the -sources flag of the command, such as -sources dogbreedslist,kennelclub.
+func DefaultSources() []Source {
+	return []Source{&DogBreedsList{}}
+}